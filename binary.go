@@ -0,0 +1,91 @@
+package tracecontext
+
+import (
+	"encoding/hex"
+	"errors"
+)
+
+// Field ids of the W3C binary trace context format draft
+const (
+	binaryTraceIdField  byte = 0
+	binaryParentIdField byte = 1
+	binaryFlagsField    byte = 2
+)
+
+// binaryLength is the length of the binary format with all fields present
+const binaryLength = 29
+
+// MarshalBinary encodes the TraceParent in the W3C binary trace context format
+// draft for non-HTTP transports: the version byte followed by the trace id,
+// parent id and trace flags fields, each prefixed with its field id. The
+// tracestate is not part of the format and is not encoded.
+func (tc *TraceContext) MarshalBinary() ([]byte, error) {
+	if tc.TraceParent == nil {
+		return nil, errors.New("TraceContext without TraceParent cannot be marshaled")
+	}
+	traceId, err := hex.DecodeString(tc.TraceParent.traceId)
+	if err != nil || len(traceId) != 16 {
+		return nil, errors.New("traceId doesn't match the specified pattern")
+	}
+	parentId, err := hex.DecodeString(tc.TraceParent.parentId)
+	if err != nil || len(parentId) != 8 {
+		return nil, errors.New("parentId doesn't match the specified pattern")
+	}
+
+	b := make([]byte, 0, binaryLength)
+	b = append(b, tc.TraceParent.version)
+	b = append(b, binaryTraceIdField)
+	b = append(b, traceId...)
+	b = append(b, binaryParentIdField)
+	b = append(b, parentId...)
+	b = append(b, binaryFlagsField, tc.TraceParent.flags)
+	return b, nil
+}
+
+// UnmarshalBinary decodes the W3C binary trace context format draft written by
+// MarshalBinary into the TraceContext. The trace flags field is optional and
+// defaults to 0. As the tracestate is not part of the format, the TraceState
+// is reset to an empty list.
+//
+// Like ParseTraceParent, a higher version is downgraded to the highest
+// supported version and OnVersionDowngrade is called. It must carry the trace
+// flags field, and any data following it is ignored. StrictHigherVersion has
+// no effect, as the format of the trailing fields of a higher version is
+// unknown. Version 255 is rejected with ErrInvalidVersion.
+func (tc *TraceContext) UnmarshalBinary(data []byte) error {
+	if len(data) == 0 {
+		return errors.New("unsupported binary format version")
+	}
+	version := data[0]
+	if version == 255 {
+		return ErrInvalidVersion
+	}
+	if len(data) < 27 || data[1] != binaryTraceIdField || data[18] != binaryParentIdField {
+		return errors.New("binary format is missing the trace id or parent id field")
+	}
+	hasFlags := len(data) >= binaryLength && data[27] == binaryFlagsField
+	if version > HighestSupportedTraceContextVersion && !hasFlags {
+		return errors.New("binary format of a higher version is missing the flags field")
+	}
+
+	tp := TraceParent{
+		version:  HighestSupportedTraceContextVersion,
+		traceId:  hex.EncodeToString(data[2:18]),
+		parentId: hex.EncodeToString(data[19:27]),
+	}
+	if hasFlags {
+		tp.flags = data[28]
+	}
+	err := tp.validate()
+	if err != nil {
+		return err
+	}
+
+	if version > HighestSupportedTraceContextVersion && OnVersionDowngrade != nil {
+		OnVersionDowngrade(version)
+	}
+
+	tc.TraceParent = &tp
+	tc.TraceState = NewEmptyTraceState()
+	return nil
+}
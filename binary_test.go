@@ -0,0 +1,104 @@
+package tracecontext
+
+import (
+	"bytes"
+	"encoding/hex"
+	"net/http"
+	"testing"
+)
+
+func TestMarshalBinary(t *testing.T) {
+	tc, _ := NewTraceContext("0af7651916cd43dd8448eb211c80319c", "00f067aa0ba902b7")
+	tc.TraceParent.SetSampled(true)
+
+	b, err := tc.MarshalBinary()
+
+	if err != nil {
+		t.Error("Failed to marshal trace context:", err)
+	}
+	expected, _ := hex.DecodeString("00000af7651916cd43dd8448eb211c80319c0100f067aa0ba902b70201")
+	if !bytes.Equal(b, expected) {
+		t.Errorf("Wrong binary representation %x", b)
+	}
+}
+
+func TestBinaryRoundTrip(t *testing.T) {
+	headers := http.Header{}
+	headers.Add(TraceParentHeader, "00-0af7651916cd43dd8448eb211c80319c-00f067aa0ba902b7-03")
+	tc, _ := ParseTraceContext(headers)
+
+	b, _ := tc.MarshalBinary()
+	decoded := TraceContext{}
+	err := decoded.UnmarshalBinary(b)
+
+	if err != nil {
+		t.Error("Failed to unmarshal trace context:", err)
+	}
+	if decoded.TraceParent.String() != tc.TraceParent.String() {
+		t.Errorf("Round trip failed: '%s'", decoded.TraceParent.String())
+	}
+	if decoded.TraceState.Len() != 0 {
+		t.Error("tracestate not reset")
+	}
+}
+
+func TestUnmarshalBinaryWithoutFlags(t *testing.T) {
+	b, _ := hex.DecodeString("00000af7651916cd43dd8448eb211c80319c0100f067aa0ba902b7")
+	tc := TraceContext{}
+
+	err := tc.UnmarshalBinary(b)
+
+	if err != nil {
+		t.Error("Failed to unmarshal trace context without flags:", err)
+	}
+	if tc.TraceParent.Flags() != 0 {
+		t.Error("Wrong default flags")
+	}
+}
+
+func TestUnmarshalBinaryHigherVersion(t *testing.T) {
+	var downgraded []uint8
+	OnVersionDowngrade = func(received uint8) {
+		downgraded = append(downgraded, received)
+	}
+	defer func() { OnVersionDowngrade = nil }()
+	b, _ := hex.DecodeString("01000af7651916cd43dd8448eb211c80319c0100f067aa0ba902b70201030102")
+	tc := TraceContext{}
+
+	err := tc.UnmarshalBinary(b)
+
+	if err != nil {
+		t.Error("Failed to unmarshal higher version:", err)
+	}
+	if tc.TraceParent.String() != "00-0af7651916cd43dd8448eb211c80319c-00f067aa0ba902b7-01" {
+		t.Error("Higher version not downgraded:", tc.TraceParent.String())
+	}
+	if len(downgraded) != 1 || downgraded[0] != 1 {
+		t.Error("OnVersionDowngrade not called with the received version:", downgraded)
+	}
+
+	b, _ = hex.DecodeString("ff000af7651916cd43dd8448eb211c80319c0100f067aa0ba902b70201")
+	if err := tc.UnmarshalBinary(b); err != ErrInvalidVersion {
+		t.Error("Wrong error for version ff:", err)
+	}
+}
+
+func TestUnmarshalBinaryInvalid(t *testing.T) {
+	inputs := []string{
+		"",
+		"ff000af7651916cd43dd8448eb211c80319c0100f067aa0ba902b70201",
+		"01000af7651916cd43dd8448eb211c80319c0100f067aa0ba902b7",
+		"00000af7651916cd43dd8448eb211c80319c",
+		"00010af7651916cd43dd8448eb211c80319c0000f067aa0ba902b70201",
+		"000000000000000000000000000000000000010000f067aa0ba902b70201",
+		"00000af7651916cd43dd8448eb211c80319c0100000000000000000201",
+	}
+
+	for _, input := range inputs {
+		b, _ := hex.DecodeString(input)
+		tc := TraceContext{}
+		if err := tc.UnmarshalBinary(b); err == nil {
+			t.Errorf("Invalid binary representation %s accepted", input)
+		}
+	}
+}
@@ -0,0 +1,81 @@
+package tracecontext
+
+import (
+	"net/http"
+	"strings"
+)
+
+// Report holds the results of the conformance checks of ConformanceReport
+type Report struct {
+	// TraceParentValid is true if there is exactly one traceparent header
+	// and it can be parsed
+	TraceParentValid bool
+	// TraceStateValid is true if the combined tracestate headers can be
+	// parsed. An absent tracestate is valid.
+	TraceStateValid bool
+	// WithinSizeLimits is true if the tracestate has at most MaxMembers
+	// members of at most MaxMemberLength characters each and doesn't exceed
+	// MaxTraceStateHeaderBytes
+	WithinSizeLimits bool
+	// NoDuplicateKeys is true if no tracestate key occurs more than once
+	NoDuplicateKeys bool
+}
+
+// Score returns the fraction of passed checks between 0 and 1
+func (r Report) Score() float64 {
+	passed := 0
+	for _, ok := range []bool{r.TraceParentValid, r.TraceStateValid, r.WithinSizeLimits, r.NoDuplicateKeys} {
+		if ok {
+			passed++
+		}
+	}
+	return float64(passed) / 4
+}
+
+// ConformanceReport checks the trace context headers for conformance with the
+// W3C specification, e.g. for a compliance dashboard. Unlike
+// ParseTraceContext, all checks are performed independently of each other.
+// Multiple tracestate headers are combined as mandated by the specification.
+func ConformanceReport(headers http.Header) Report {
+	report := Report{
+		WithinSizeLimits: true,
+		NoDuplicateKeys:  true,
+	}
+
+	traceParents := headers.Values(TraceParentHeader)
+	if len(traceParents) == 1 {
+		_, err := ParseTraceParent(traceParents[0])
+		report.TraceParentValid = err == nil
+	}
+
+	traceState := strings.Join(headers.Values(TraceStateHeader), ",")
+	_, err := ParseTraceState(traceState)
+	report.TraceStateValid = err == nil
+
+	if MaxTraceStateHeaderBytes > 0 && len(traceState) > MaxTraceStateHeaderBytes {
+		report.WithinSizeLimits = false
+	}
+	members := 0
+	keys := map[string]struct{}{}
+	for _, candidate := range appendSplit(nil, traceState, ',') {
+		candidate = strings.Trim(candidate, " \t")
+		if len(candidate) == 0 {
+			continue
+		}
+		members++
+		if len(candidate) > MaxMemberLength {
+			report.WithinSizeLimits = false
+		}
+
+		key, _, _ := strings.Cut(candidate, "=")
+		if _, ok := keys[key]; ok {
+			report.NoDuplicateKeys = false
+		}
+		keys[key] = struct{}{}
+	}
+	if members > MaxMembers {
+		report.WithinSizeLimits = false
+	}
+
+	return report
+}
@@ -0,0 +1,60 @@
+package tracecontext
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestConformanceReport(t *testing.T) {
+	headers := http.Header{}
+	headers.Add(TraceParentHeader, "00-0af7651916cd43dd8448eb211c80319c-00f067aa0ba902b7-01")
+	headers.Add(TraceStateHeader, "vendor1=val1")
+	headers.Add(TraceStateHeader, "vendor2=val2")
+
+	report := ConformanceReport(headers)
+
+	expected := Report{TraceParentValid: true, TraceStateValid: true, WithinSizeLimits: true, NoDuplicateKeys: true}
+	if report != expected {
+		t.Errorf("Wrong report %+v", report)
+	}
+	if report.Score() != 1 {
+		t.Errorf("Wrong score %f", report.Score())
+	}
+}
+
+func TestConformanceReportInvalid(t *testing.T) {
+	members := make([]string, MaxMembers)
+	for i := range members {
+		members[i] = fmt.Sprintf("vendor%d=val", i)
+	}
+	headers := http.Header{}
+	headers.Add(TraceParentHeader, "00-0af7651916cd43dd8448eb211c80319c-00f067aa0ba902b7-01")
+	headers.Add(TraceParentHeader, "00-0af7651916cd43dd8448eb211c80319c-00f067aa0ba902b7-01")
+	headers.Add(TraceStateHeader, strings.Join(members, ",")+",vendor0=val,Invalid=val")
+
+	report := ConformanceReport(headers)
+
+	if report != (Report{}) {
+		t.Errorf("Wrong report %+v", report)
+	}
+	if report.Score() != 0 {
+		t.Errorf("Wrong score %f", report.Score())
+	}
+}
+
+func TestConformanceReportMemberTooLong(t *testing.T) {
+	headers := http.Header{}
+	headers.Add(TraceParentHeader, "00-0af7651916cd43dd8448eb211c80319c-00f067aa0ba902b7-01")
+	headers.Add(TraceStateHeader, "vendor1="+strings.Repeat("a", MaxMemberLength)+",vendor1=val")
+
+	report := ConformanceReport(headers)
+
+	if !report.TraceParentValid || report.WithinSizeLimits || report.NoDuplicateKeys {
+		t.Errorf("Wrong report %+v", report)
+	}
+	if report.Score() != 0.5 {
+		t.Errorf("Wrong score %f", report.Score())
+	}
+}
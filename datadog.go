@@ -0,0 +1,76 @@
+package tracecontext
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+)
+
+const (
+	DatadogTraceIdHeader          = "x-datadog-trace-id"
+	DatadogParentIdHeader         = "x-datadog-parent-id"
+	DatadogSamplingPriorityHeader = "x-datadog-sampling-priority"
+)
+
+// ParseDatadog attempts to extract TraceContext information from the Datadog
+// propagation headers. The 64 bit decimal trace id is mapped to the lower 64
+// bits of the W3C trace id and a positive sampling priority sets the sampled
+// flag.
+func ParseDatadog(headers http.Header) (*TraceContext, error) {
+	traceId, err := strconv.ParseUint(headers.Get(DatadogTraceIdHeader), 10, 64)
+	if err != nil {
+		return nil, errors.New("cannot parse datadog trace id")
+	}
+	if traceId == 0 {
+		return nil, errors.New("all zero trace id is not allowed")
+	}
+	parentId, err := strconv.ParseUint(headers.Get(DatadogParentIdHeader), 10, 64)
+	if err != nil {
+		return nil, errors.New("cannot parse datadog parent id")
+	}
+	if parentId == 0 {
+		return nil, errors.New("all zero parent id is not allowed")
+	}
+
+	tc, err := NewTraceContext(fmt.Sprintf("%032x", traceId), fmt.Sprintf("%016x", parentId))
+	if err != nil {
+		return nil, err
+	}
+
+	if priority := headers.Get(DatadogSamplingPriorityHeader); priority != "" {
+		parsedPriority, err := strconv.Atoi(priority)
+		if err != nil {
+			return nil, errors.New("cannot parse datadog sampling priority")
+		}
+		tc.TraceParent.SetSampled(parsedPriority > 0)
+	}
+
+	return tc, nil
+}
+
+// WriteDatadogHeaders writes the Datadog propagation headers to the provided
+// headers object. Only the lower 64 bits of the trace id are written as
+// Datadog trace ids are 64 bit. Any existing headers of the same name are
+// overwritten. Nothing is written if the lower 64 bits of the trace id are
+// zero, as Datadog treats the trace id 0 as invalid.
+func (tc *TraceContext) WriteDatadogHeaders(headers *http.Header) {
+	if tc.TraceParent == nil {
+		return
+	}
+
+	// The ids always match the hex patterns
+	traceId, _ := strconv.ParseUint(tc.TraceParent.traceId[16:], 16, 64)
+	if traceId == 0 {
+		return
+	}
+	parentId, _ := strconv.ParseUint(tc.TraceParent.parentId, 16, 64)
+	priority := "0"
+	if tc.TraceParent.IsSampled() {
+		priority = "1"
+	}
+
+	headers.Set(DatadogTraceIdHeader, strconv.FormatUint(traceId, 10))
+	headers.Set(DatadogParentIdHeader, strconv.FormatUint(parentId, 10))
+	headers.Set(DatadogSamplingPriorityHeader, priority)
+}
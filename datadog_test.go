@@ -0,0 +1,97 @@
+package tracecontext
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestParseDatadog(t *testing.T) {
+	headers := http.Header{}
+	headers.Add(DatadogTraceIdHeader, "9532127138774266268")
+	headers.Add(DatadogParentIdHeader, "67667974448284343")
+	headers.Add(DatadogSamplingPriorityHeader, "2")
+
+	tc, err := ParseDatadog(headers)
+
+	if err != nil {
+		t.Error("Failed to parse datadog headers:", err)
+	}
+	if tc.TraceParent.TraceId() != "00000000000000008448eb211c80319c" {
+		t.Error("trace id not converted correctly", tc.TraceParent.TraceId())
+	}
+	if tc.TraceParent.ParentId() != "00f067aa0ba902b7" {
+		t.Error("parent id not converted correctly", tc.TraceParent.ParentId())
+	}
+	if !tc.TraceParent.IsSampled() {
+		t.Error("sampling priority not mapped correctly")
+	}
+}
+
+func TestParseDatadogSamplingPriority(t *testing.T) {
+	expected := map[string]bool{"-1": false, "0": false, "1": true, "2": true, "": false}
+
+	for priority, sampled := range expected {
+		headers := http.Header{}
+		headers.Add(DatadogTraceIdHeader, "1")
+		headers.Add(DatadogParentIdHeader, "1")
+		headers.Add(DatadogSamplingPriorityHeader, priority)
+
+		tc, err := ParseDatadog(headers)
+		if err != nil {
+			t.Error("Failed to parse datadog headers:", err)
+			continue
+		}
+		if tc.TraceParent.IsSampled() != sampled {
+			t.Errorf("sampling priority '%s' not mapped correctly", priority)
+		}
+	}
+}
+
+func TestParseDatadogInvalid(t *testing.T) {
+	inputs := [][2]string{
+		{"", "1"},
+		{"1", ""},
+		{"0", "1"},
+		{"1", "0"},
+		{"abc", "1"},
+		{"18446744073709551616", "1"},
+	}
+
+	for _, input := range inputs {
+		headers := http.Header{}
+		headers.Add(DatadogTraceIdHeader, input[0])
+		headers.Add(DatadogParentIdHeader, input[1])
+		if _, err := ParseDatadog(headers); err == nil {
+			t.Errorf("Parsed invalid ids %v", input)
+		}
+	}
+}
+
+func TestWriteDatadogHeaders(t *testing.T) {
+	tc, _ := NewTraceContext("0af7651916cd43dd8448eb211c80319c", "00f067aa0ba902b7")
+	tc.TraceParent.SetSampled(true)
+	headers := http.Header{}
+
+	tc.WriteDatadogHeaders(&headers)
+
+	if headers.Get(DatadogTraceIdHeader) != "9532127138774266268" {
+		t.Errorf("Wrong trace id written: '%s'", headers.Get(DatadogTraceIdHeader))
+	}
+	if headers.Get(DatadogParentIdHeader) != "67667974448284343" {
+		t.Errorf("Wrong parent id written: '%s'", headers.Get(DatadogParentIdHeader))
+	}
+	if headers.Get(DatadogSamplingPriorityHeader) != "1" {
+		t.Errorf("Wrong sampling priority written: '%s'", headers.Get(DatadogSamplingPriorityHeader))
+	}
+}
+
+func TestWriteDatadogHeadersZeroLowerTraceId(t *testing.T) {
+	tc, _ := NewTraceContext("0af7651916cd43dd0000000000000000", "00f067aa0ba902b7")
+	headers := http.Header{}
+
+	tc.WriteDatadogHeaders(&headers)
+
+	if headers.Get(DatadogTraceIdHeader) != "" || headers.Get(DatadogParentIdHeader) != "" {
+		t.Errorf("Invalid Datadog trace id written: '%s'", headers.Get(DatadogTraceIdHeader))
+	}
+}
@@ -0,0 +1,34 @@
+package tracecontext
+
+import (
+	"net/http"
+	"os"
+)
+
+const (
+	TraceParentEnv = "TRACEPARENT"
+	TraceStateEnv  = "TRACESTATE"
+)
+
+// ParseTraceContextEnv attempts to extract TraceContext information from the
+// TRACEPARENT and TRACESTATE environment variables. It behaves like
+// ParseTraceContext otherwise.
+func ParseTraceContextEnv() (*TraceContext, error) {
+	headers := http.Header{}
+	headers.Set(TraceParentHeader, os.Getenv(TraceParentEnv))
+	headers.Set(TraceStateHeader, os.Getenv(TraceStateEnv))
+
+	return ParseTraceContext(headers)
+}
+
+// WriteEnv passes the TRACEPARENT and TRACESTATE environment variables to the
+// provided setter, e.g. to populate the environment of a child process.
+// Like WriteHeaders, an empty tracestate is not written.
+func (tc *TraceContext) WriteEnv(set func(key, val string)) {
+	if tc.TraceParent != nil {
+		set(TraceParentEnv, tc.TraceParent.String())
+	}
+	if tc.TraceState != nil && len(tc.TraceState.Members) > 0 {
+		set(TraceStateEnv, tc.TraceState.String())
+	}
+}
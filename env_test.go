@@ -0,0 +1,51 @@
+package tracecontext
+
+import (
+	"testing"
+)
+
+func TestParseTraceContextEnv(t *testing.T) {
+	t.Setenv(TraceParentEnv, "00-0af7651916cd43dd8448eb211c80319c-00f067aa0ba902b7-01")
+	t.Setenv(TraceStateEnv, "vendor1=val1")
+
+	tc, err := ParseTraceContextEnv()
+
+	if err != nil {
+		t.Error("Failed to parse trace context:", err)
+	}
+	if tc.TraceParent.String() != "00-0af7651916cd43dd8448eb211c80319c-00f067aa0ba902b7-01" {
+		t.Error("traceparent not parsed correctly")
+	}
+	if tc.TraceState.String() != "vendor1=val1" {
+		t.Error("tracestate not parsed correctly")
+	}
+}
+
+func TestParseTraceContextEnvMissing(t *testing.T) {
+	t.Setenv(TraceParentEnv, "")
+
+	_, err := ParseTraceContextEnv()
+
+	if err == nil {
+		t.Error("Parsed trace context without TRACEPARENT")
+	}
+}
+
+func TestWriteEnv(t *testing.T) {
+	tc, _ := NewTraceContext("0af7651916cd43dd8448eb211c80319c", "00f067aa0ba902b7")
+	env := map[string]string{}
+	set := func(key, val string) {
+		env[key] = val
+	}
+
+	tc.WriteEnv(set)
+	if len(env) != 1 || env[TraceParentEnv] != "00-0af7651916cd43dd8448eb211c80319c-00f067aa0ba902b7-00" {
+		t.Errorf("Wrong environment written: %v", env)
+	}
+
+	tc.TraceState.Mutate(TraceStateMember{Key: "vendor1", Value: "val1"})
+	tc.WriteEnv(set)
+	if env[TraceStateEnv] != "vendor1=val1" {
+		t.Errorf("Wrong environment written: %v", env)
+	}
+}
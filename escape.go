@@ -0,0 +1,50 @@
+package tracecontext
+
+import (
+	"fmt"
+	"strings"
+)
+
+// EncodeValue percent-encodes all bytes of v that are not allowed in a
+// tracestate value (e.g. commas and equals signs), as well as spaces and the
+// percent sign itself. This is a non-standard extension and must only be used
+// for members that are exclusively read by the same vendor, which decodes them
+// with DecodeValue.
+func EncodeValue(v string) string {
+	sb := strings.Builder{}
+	for i := 0; i < len(v); i++ {
+		c := v[i]
+		if c <= 0x20 || c > 0x7e || c == ',' || c == '=' || c == '%' {
+			sb.WriteString(fmt.Sprintf("%%%02X", c))
+		} else {
+			sb.WriteByte(c)
+		}
+	}
+	return sb.String()
+}
+
+// DecodeValue reverses EncodeValue. Percent signs that are not followed by two
+// hex characters are left unchanged.
+func DecodeValue(v string) string {
+	sb := strings.Builder{}
+	for i := 0; i < len(v); i++ {
+		if v[i] == '%' && i+2 < len(v) && isHexChar(v[i+1]) && isHexChar(v[i+2]) {
+			sb.WriteByte(hexNibble(toLowerHex(v[i+1]))<<4 | hexNibble(toLowerHex(v[i+2])))
+			i += 2
+		} else {
+			sb.WriteByte(v[i])
+		}
+	}
+	return sb.String()
+}
+
+func isHexChar(c byte) bool {
+	return c >= '0' && c <= '9' || c >= 'a' && c <= 'f' || c >= 'A' && c <= 'F'
+}
+
+func toLowerHex(c byte) byte {
+	if c >= 'A' && c <= 'F' {
+		return c + 'a' - 'A'
+	}
+	return c
+}
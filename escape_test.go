@@ -0,0 +1,30 @@
+package tracecontext
+
+import (
+	"testing"
+)
+
+func TestEncodeValue(t *testing.T) {
+	value := "a=1,b=2 100%"
+
+	encoded := EncodeValue(value)
+
+	if encoded != "a%3D1%2Cb%3D2%20100%25" {
+		t.Errorf("Wrong encoded value: '%s'", encoded)
+	}
+	if !valuePattern.MatchString(encoded) {
+		t.Error("Encoded value is not a valid tracestate value")
+	}
+	if decoded := DecodeValue(encoded); decoded != value {
+		t.Errorf("Wrong decoded value: '%s'", decoded)
+	}
+}
+
+func TestDecodeValueInvalidEscape(t *testing.T) {
+	if decoded := DecodeValue("100%"); decoded != "100%" {
+		t.Errorf("Wrong decoded value: '%s'", decoded)
+	}
+	if decoded := DecodeValue("%zz%2c"); decoded != "%zz," {
+		t.Errorf("Wrong decoded value: '%s'", decoded)
+	}
+}
@@ -0,0 +1,77 @@
+package tracecontext
+
+import (
+	"net/http"
+)
+
+// FrozenTraceContext is a read-only snapshot of a TraceContext. It only
+// exposes getters and WriteHeaders, so it can be passed on without the risk
+// of accidental mutation.
+type FrozenTraceContext struct {
+	tc TraceContext
+}
+
+// Freeze returns a read-only snapshot of the TraceContext. Later changes to
+// the TraceContext don't affect the snapshot.
+func (tc *TraceContext) Freeze() *FrozenTraceContext {
+	frozen := FrozenTraceContext{}
+	if tc.TraceParent != nil {
+		tp := *tc.TraceParent
+		frozen.tc.TraceParent = &tp
+	}
+	if tc.TraceState != nil {
+		frozen.tc.TraceState = tc.TraceState.clone()
+	}
+	return &frozen
+}
+
+// TraceId returns the trace id or an empty string if there is no TraceParent
+func (f *FrozenTraceContext) TraceId() string {
+	if f.tc.TraceParent == nil {
+		return ""
+	}
+	return f.tc.TraceParent.TraceId()
+}
+
+// ParentId returns the parent id or an empty string if there is no
+// TraceParent
+func (f *FrozenTraceContext) ParentId() string {
+	if f.tc.TraceParent == nil {
+		return ""
+	}
+	return f.tc.TraceParent.ParentId()
+}
+
+// IsSampled returns true if the sampled flag is set
+func (f *FrozenTraceContext) IsSampled() bool {
+	return f.tc.TraceParent != nil && f.tc.TraceParent.IsSampled()
+}
+
+// TraceParent returns the traceparent header value or an empty string if
+// there is no TraceParent
+func (f *FrozenTraceContext) TraceParent() string {
+	return f.tc.TraceParentHeaderValue()
+}
+
+// TraceState returns the tracestate header value
+func (f *FrozenTraceContext) TraceState() string {
+	if f.tc.TraceState == nil {
+		return ""
+	}
+	return f.tc.TraceState.String()
+}
+
+// MemberValue returns the tracestate value of the member with the provided
+// key. If the member doesn't exist, an empty string is returned.
+func (f *FrozenTraceContext) MemberValue(memberKey string) string {
+	if f.tc.TraceState == nil {
+		return ""
+	}
+	return f.tc.TraceState.MemberValue(memberKey)
+}
+
+// WriteHeaders writes the traceparent and tracestate headers like
+// TraceContext.WriteHeaders
+func (f *FrozenTraceContext) WriteHeaders(headers *http.Header) {
+	f.tc.WriteHeaders(headers)
+}
@@ -0,0 +1,59 @@
+package tracecontext
+
+import (
+	"net/http"
+	"sync"
+	"testing"
+)
+
+func TestFreeze(t *testing.T) {
+	tc, _ := NewTraceContext("0af7651916cd43dd8448eb211c80319c", "00f067aa0ba902b7")
+	tc.TraceState.Mutate(TraceStateMember{Key: "vendor1", Value: "val1"})
+
+	frozen := tc.Freeze()
+	tc.Mutate("", SamplingBehaviorAlwaysSampled, &TraceStateMember{Key: "vendor2", Value: "val2"})
+
+	if frozen.TraceId() != "0af7651916cd43dd8448eb211c80319c" {
+		t.Error("trace id not exposed")
+	}
+	if frozen.ParentId() != "00f067aa0ba902b7" || frozen.IsSampled() {
+		t.Error("snapshot affected by mutation")
+	}
+	if frozen.TraceState() != "vendor1=val1" || frozen.MemberValue("vendor1") != "val1" {
+		t.Error("snapshot tracestate affected by mutation")
+	}
+
+	if _, ok := any(frozen).(interface {
+		Mutate(string, SamplingBehavior, *TraceStateMember) error
+	}); ok {
+		t.Error("frozen trace context exposes Mutate")
+	}
+
+	headers := http.Header{}
+	frozen.WriteHeaders(&headers)
+	if headers.Get(TraceParentHeader) != "00-0af7651916cd43dd8448eb211c80319c-00f067aa0ba902b7-00" {
+		t.Errorf("Wrong traceparent header written: '%s'", headers.Get(TraceParentHeader))
+	}
+}
+
+// TestFreezeConcurrentWriteHeaders is meant to be run with -race to verify
+// that a shared snapshot isn't modified while writing headers
+func TestFreezeConcurrentWriteHeaders(t *testing.T) {
+	tc, _ := NewTraceContext("0af7651916cd43dd8448eb211c80319c", "00f067aa0ba902b7")
+	tc.TraceState.Mutate(TraceStateMember{Key: "vendor1", Value: "val1"})
+	frozen := tc.Freeze()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			headers := http.Header{}
+			frozen.WriteHeaders(&headers)
+			if headers.Get(TraceStateHeader) != "vendor1=val1" {
+				t.Errorf("Wrong tracestate header written: '%s'", headers.Get(TraceStateHeader))
+			}
+		}()
+	}
+	wg.Wait()
+}
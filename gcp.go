@@ -0,0 +1,70 @@
+package tracecontext
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+const (
+	GCPTraceContextHeader = "X-Cloud-Trace-Context"
+)
+
+// ParseGCPTrace attempts to extract TraceContext information from the Google
+// Cloud X-Cloud-Trace-Context header, which has the format
+// TRACE_ID/SPAN_ID;o=TRACE_TRUE. The decimal span id is converted to the
+// hex parent id format and o=1 is mapped to the sampled flag.
+func ParseGCPTrace(headers http.Header) (*TraceContext, error) {
+	s := headers.Get(GCPTraceContextHeader)
+	if s == "" {
+		return nil, errors.New("missing X-Cloud-Trace-Context header")
+	}
+
+	traceId, rest, found := strings.Cut(s, "/")
+	if !found {
+		return nil, errors.New("X-Cloud-Trace-Context header is missing the span id")
+	}
+	spanId, options, _ := strings.Cut(rest, ";")
+
+	parsedSpanId, err := strconv.ParseUint(spanId, 10, 64)
+	if err != nil {
+		return nil, errors.New("cannot parse span id")
+	}
+	if parsedSpanId == 0 {
+		return nil, errors.New("all zero parent id is not allowed")
+	}
+
+	tc, err := NewTraceContext(strings.ToLower(traceId), fmt.Sprintf("%016x", parsedSpanId))
+	if err != nil {
+		return nil, err
+	}
+	if tc.TraceParent.traceId == "00000000000000000000000000000000" {
+		return nil, errors.New("all zero trace id is not allowed")
+	}
+
+	tc.TraceParent.SetSampled(options == "o=1")
+
+	return tc, nil
+}
+
+// WriteGCPHeaders writes the X-Cloud-Trace-Context header to the provided
+// headers object. Any existing header of the same name is overwritten.
+func (tc *TraceContext) WriteGCPHeaders(headers *http.Header) {
+	if tc.TraceParent == nil {
+		return
+	}
+
+	// The parent id always matches the 16 hex character pattern
+	spanId, _ := strconv.ParseUint(tc.TraceParent.parentId, 16, 64)
+	sampled := 0
+	if tc.TraceParent.IsSampled() {
+		sampled = 1
+	}
+
+	headers.Set(GCPTraceContextHeader, fmt.Sprintf("%s/%d;o=%d",
+		tc.TraceParent.traceId,
+		spanId,
+		sampled))
+}
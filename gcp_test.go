@@ -0,0 +1,77 @@
+package tracecontext
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestParseGCPTrace(t *testing.T) {
+	headers := http.Header{}
+	headers.Add(GCPTraceContextHeader, "0af7651916cd43dd8448eb211c80319c/67667974448284343;o=1")
+
+	tc, err := ParseGCPTrace(headers)
+
+	if err != nil {
+		t.Error("Failed to parse GCP trace context:", err)
+	}
+	if tc.TraceParent.TraceId() != "0af7651916cd43dd8448eb211c80319c" {
+		t.Error("trace id not parsed correctly")
+	}
+	if tc.TraceParent.ParentId() != "00f067aa0ba902b7" {
+		t.Error("parent id not converted correctly", tc.TraceParent.ParentId())
+	}
+	if !tc.TraceParent.IsSampled() {
+		t.Error("sampled flag not parsed correctly")
+	}
+}
+
+func TestParseGCPTraceNotSampled(t *testing.T) {
+	headers := http.Header{}
+	headers.Add(GCPTraceContextHeader, "0af7651916cd43dd8448eb211c80319c/1")
+
+	tc, err := ParseGCPTrace(headers)
+
+	if err != nil {
+		t.Error("Failed to parse GCP trace context:", err)
+	}
+	if tc.TraceParent.IsSampled() {
+		t.Error("sampled flag not parsed correctly")
+	}
+}
+
+func TestParseGCPTraceInvalid(t *testing.T) {
+	inputs := []string{
+		"",
+		"0af7651916cd43dd8448eb211c80319c",
+		"0af7651916cd43dd8448eb211c80319c/abc;o=1",
+		"0af7651916cd43dd8448eb211c80319c/0;o=1",
+		"00000000000000000000000000000000/1;o=1",
+		"0af7651916cd43dd/1;o=1",
+	}
+
+	for _, input := range inputs {
+		headers := http.Header{}
+		headers.Add(GCPTraceContextHeader, input)
+		if _, err := ParseGCPTrace(headers); err == nil {
+			t.Errorf("Parsed invalid header '%s'", input)
+		}
+	}
+}
+
+func TestGCPTraceRoundTrip(t *testing.T) {
+	input := "0af7651916cd43dd8448eb211c80319c/67667974448284343;o=1"
+	headers := http.Header{}
+	headers.Add(GCPTraceContextHeader, input)
+
+	tc, err := ParseGCPTrace(headers)
+	if err != nil {
+		t.Error("Failed to parse GCP trace context:", err)
+	}
+
+	newHeaders := http.Header{}
+	tc.WriteGCPHeaders(&newHeaders)
+
+	if newHeaders.Get(GCPTraceContextHeader) != input {
+		t.Errorf("Wrong header written: '%s'", newHeaders.Get(GCPTraceContextHeader))
+	}
+}
@@ -1,3 +1,13 @@
 module github.com/garciasdos/w3c-trace-context
 
 go 1.21
+
+require (
+	github.com/fxamacker/cbor/v2 v2.9.0
+	go.opentelemetry.io/otel/trace v1.28.0
+)
+
+require (
+	github.com/x448/float16 v0.8.4 // indirect
+	go.opentelemetry.io/otel v1.28.0 // indirect
+)
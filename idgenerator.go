@@ -0,0 +1,52 @@
+package tracecontext
+
+import "errors"
+
+// IDGenerator generates the trace ids and parent ids of new trace contexts.
+// Trace ids must consist of 32 and span ids of 16 lowercase hex characters.
+type IDGenerator interface {
+	NewTraceID() (string, error)
+	NewSpanID() (string, error)
+}
+
+// randomIDGenerator is the default IDGenerator using crypto/rand
+type randomIDGenerator struct{}
+
+func (randomIDGenerator) NewTraceID() (string, error) {
+	return randomHex(16)
+}
+
+func (randomIDGenerator) NewSpanID() (string, error) {
+	return randomHex(8)
+}
+
+var idGenerator IDGenerator = randomIDGenerator{}
+
+// SetIDGenerator replaces the IDGenerator used for all generated ids. Passing
+// nil restores the default crypto-random generator. It is not safe to call
+// this concurrently with the generation of trace contexts.
+func SetIDGenerator(g IDGenerator) {
+	if g == nil {
+		g = randomIDGenerator{}
+	}
+	idGenerator = g
+}
+
+// maxSpanIDAttempts limits how often newSpanIDDistinctFrom asks the generator
+// for a new id, so that a broken generator cannot cause an endless loop
+const maxSpanIDAttempts = 8
+
+// newSpanIDDistinctFrom returns a new span id that differs from the provided
+// one, regenerating it if the generator returned the same id
+func newSpanIDDistinctFrom(old string) (string, error) {
+	for i := 0; i < maxSpanIDAttempts; i++ {
+		id, err := idGenerator.NewSpanID()
+		if err != nil {
+			return "", err
+		}
+		if id != old {
+			return id, nil
+		}
+	}
+	return "", errors.New("id generator repeatedly returned the same span id")
+}
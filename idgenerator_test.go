@@ -0,0 +1,87 @@
+package tracecontext
+
+import (
+	"testing"
+)
+
+type fixedIDGenerator struct{}
+
+func (fixedIDGenerator) NewTraceID() (string, error) {
+	return "0af7651916cd43dd8448eb211c80319c", nil
+}
+
+func (fixedIDGenerator) NewSpanID() (string, error) {
+	return "00f067aa0ba902b7", nil
+}
+
+// sequenceIDGenerator returns the span ids in order and repeats the last one
+type sequenceIDGenerator struct {
+	spanIds []string
+}
+
+func (g *sequenceIDGenerator) NewTraceID() (string, error) {
+	return "0af7651916cd43dd8448eb211c80319c", nil
+}
+
+func (g *sequenceIDGenerator) NewSpanID() (string, error) {
+	id := g.spanIds[0]
+	if len(g.spanIds) > 1 {
+		g.spanIds = g.spanIds[1:]
+	}
+	return id, nil
+}
+
+func TestSetIDGenerator(t *testing.T) {
+	SetIDGenerator(fixedIDGenerator{})
+	defer SetIDGenerator(nil)
+
+	tc, err := GenerateTraceContext("", nil, SamplingBehaviorPassThrough)
+
+	if err != nil {
+		t.Error("Failed to generate trace context:", err)
+	}
+	if tc.TraceParent.TraceId() != "0af7651916cd43dd8448eb211c80319c" {
+		t.Error("trace id of the generator not used")
+	}
+	if tc.TraceParent.ParentId() != "00f067aa0ba902b7" {
+		t.Error("parent id of the generator not used")
+	}
+}
+
+func TestSetIDGeneratorDefault(t *testing.T) {
+	SetIDGenerator(fixedIDGenerator{})
+	SetIDGenerator(nil)
+
+	tc, _ := GenerateTraceContext("", nil, SamplingBehaviorPassThrough)
+
+	if tc.TraceParent.TraceId() == "0af7651916cd43dd8448eb211c80319c" {
+		t.Error("default generator not restored")
+	}
+}
+
+func TestNewChildOfDistinctParentId(t *testing.T) {
+	parent, _ := NewTraceContext("0af7651916cd43dd8448eb211c80319c", "00f067aa0ba902b7")
+	SetIDGenerator(&sequenceIDGenerator{spanIds: []string{"00f067aa0ba902b7", "b7ad6b7169203331"}})
+	defer SetIDGenerator(nil)
+
+	child, err := NewChildOf(parent, nil, SamplingBehaviorPassThrough)
+
+	if err != nil {
+		t.Error("Failed to create child:", err)
+	}
+	if child.TraceParent.ParentId() != "b7ad6b7169203331" {
+		t.Errorf("parent id not regenerated: '%s'", child.TraceParent.ParentId())
+	}
+}
+
+func TestNewChildOfRepeatedParentId(t *testing.T) {
+	parent, _ := NewTraceContext("0af7651916cd43dd8448eb211c80319c", "00f067aa0ba902b7")
+	SetIDGenerator(fixedIDGenerator{})
+	defer SetIDGenerator(nil)
+
+	_, err := NewChildOf(parent, nil, SamplingBehaviorPassThrough)
+
+	if err == nil {
+		t.Error("Repeated parent id accepted")
+	}
+}
@@ -0,0 +1,60 @@
+package tracecontext
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// ParseTraceContextJSON attempts to extract TraceContext information from a
+// JSON document, e.g. a webhook request body. The paths select the fields
+// holding the traceparent and tracestate values; nested fields are selected
+// with dot-separated keys like "meta.traceparent". A missing tracestate field
+// is treated as an empty tracestate. It behaves like ParseTraceContext
+// otherwise.
+func ParseTraceContextJSON(r io.Reader, traceparentPath, tracestatePath string) (*TraceContext, error) {
+	var document any
+	err := json.NewDecoder(r).Decode(&document)
+	if err != nil {
+		return nil, err
+	}
+
+	traceparent, err := lookupJSONString(document, traceparentPath)
+	if err != nil {
+		return nil, err
+	}
+	tracestate, err := lookupJSONString(document, tracestatePath)
+	if err != nil {
+		return nil, err
+	}
+
+	headers := http.Header{}
+	headers.Set(TraceParentHeader, traceparent)
+	headers.Set(TraceStateHeader, tracestate)
+
+	return ParseTraceContext(headers)
+}
+
+// lookupJSONString returns the string at the dot-separated path of a decoded
+// JSON document. An empty string is returned if the path doesn't exist.
+func lookupJSONString(document any, path string) (string, error) {
+	value := document
+	for _, key := range strings.Split(path, ".") {
+		object, ok := value.(map[string]any)
+		if !ok {
+			return "", nil
+		}
+		value, ok = object[key]
+		if !ok {
+			return "", nil
+		}
+	}
+
+	s, ok := value.(string)
+	if !ok {
+		return "", fmt.Errorf("field '%s' is not a string", path)
+	}
+	return s, nil
+}
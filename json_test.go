@@ -0,0 +1,56 @@
+package tracecontext
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseTraceContextJSON(t *testing.T) {
+	body := `{
+		"event": "push",
+		"meta": {
+			"traceparent": "00-0af7651916cd43dd8448eb211c80319c-00f067aa0ba902b7-01",
+			"tracestate": "vendor1=val1"
+		}
+	}`
+
+	tc, err := ParseTraceContextJSON(strings.NewReader(body), "meta.traceparent", "meta.tracestate")
+
+	if err != nil {
+		t.Error("Failed to parse trace context:", err)
+	}
+	if tc.TraceParent.String() != "00-0af7651916cd43dd8448eb211c80319c-00f067aa0ba902b7-01" {
+		t.Error("traceparent not parsed correctly")
+	}
+	if tc.TraceState.String() != "vendor1=val1" {
+		t.Error("tracestate not parsed correctly")
+	}
+}
+
+func TestParseTraceContextJSONMissingTraceState(t *testing.T) {
+	body := `{"traceparent": "00-0af7651916cd43dd8448eb211c80319c-00f067aa0ba902b7-01"}`
+
+	tc, err := ParseTraceContextJSON(strings.NewReader(body), "traceparent", "tracestate")
+
+	if err != nil {
+		t.Error("Failed to parse trace context:", err)
+	}
+	if tc.TraceState == nil || len(tc.TraceState.Members) != 0 {
+		t.Error("TraceState list is not empty")
+	}
+}
+
+func TestParseTraceContextJSONInvalid(t *testing.T) {
+	bodies := []string{
+		`{"traceparent": "01-illegal"}`,
+		`{"tracestate": "vendor1=val1"}`,
+		`{"traceparent": 1}`,
+		`not json`,
+	}
+
+	for _, body := range bodies {
+		if _, err := ParseTraceContextJSON(strings.NewReader(body), "traceparent", "tracestate"); err == nil {
+			t.Errorf("Parsed invalid body '%s'", body)
+		}
+	}
+}
@@ -0,0 +1,55 @@
+package tracecontext
+
+import (
+	"net/http"
+)
+
+// MiddlewareConfig configures the trace context handling of Middleware
+type MiddlewareConfig struct {
+	// ParentId is used for every request. It is generated randomly if empty.
+	ParentId string
+	// Member is added to the tracestate of every request if it is not nil
+	Member   *TraceStateMember
+	Sampling SamplingBehavior
+	// Metrics is called for every handled request with the final sampling
+	// decision and whether an inbound trace was continued or a new one was
+	// started. It is not called if left nil.
+	Metrics func(sampled bool, continued bool)
+	// OnError is called with the request and the error if the trace context
+	// of a request cannot be handled, e.g. because Member is invalid. The
+	// request is then passed on to next unchanged. It is not called if left
+	// nil.
+	OnError func(r *http.Request, err error)
+}
+
+// Middleware returns a http.Handler that handles the trace context of each
+// request with HandleTraceContext before passing the request with the
+// mutated headers on to next.
+func Middleware(next http.Handler, config MiddlewareConfig) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var member *TraceStateMember
+		if config.Member != nil {
+			// The member is copied as an empty value is filled per request
+			m := *config.Member
+			member = &m
+		}
+
+		newHeaders, tc, err := HandleTraceContext(&r.Header, config.ParentId, member, config.Sampling)
+		if err != nil {
+			if config.OnError != nil {
+				config.OnError(r, err)
+			}
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if config.Metrics != nil {
+			// A generated trace context is root, a continued one is parsed
+			config.Metrics(tc.TraceParent.IsSampled(), !tc.IsRoot())
+		}
+
+		newRequest := *r
+		newRequest.Header = *newHeaders
+		next.ServeHTTP(w, &newRequest)
+	})
+}
@@ -0,0 +1,87 @@
+package tracecontext
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestMiddleware(t *testing.T) {
+	var received http.Header
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received = r.Header
+	})
+	handler := Middleware(next, MiddlewareConfig{
+		Member:   &TraceStateMember{Key: "vendor2"},
+		Sampling: SamplingBehaviorPassThrough,
+	})
+
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Add(TraceParentHeader, "00-0af7651916cd43dd8448eb211c80319c-00f067aa0ba902b7-01")
+	r.Header.Add(TraceStateHeader, "vendor1=val1")
+	handler.ServeHTTP(httptest.NewRecorder(), r)
+
+	tp, err := ParseTraceParent(received.Get(TraceParentHeader))
+	if err != nil {
+		t.Error("Invalid traceparent passed on:", err)
+	}
+	if tp != nil && (tp.TraceId() != "0af7651916cd43dd8448eb211c80319c" || tp.ParentId() == "00f067aa0ba902b7") {
+		t.Error("traceparent not mutated")
+	}
+	if received.Get(TraceStateHeader) != "vendor2="+tp.ParentId()+",vendor1=val1" {
+		t.Errorf("TraceState is not as expected: '%s'", received.Get(TraceStateHeader))
+	}
+}
+
+func TestMiddlewareMetrics(t *testing.T) {
+	var sampled, continued []bool
+	handler := Middleware(http.NotFoundHandler(), MiddlewareConfig{
+		Sampling: SamplingBehaviorPassThrough,
+		Metrics: func(s bool, c bool) {
+			sampled = append(sampled, s)
+			continued = append(continued, c)
+		},
+	})
+
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Add(TraceParentHeader, "00-0af7651916cd43dd8448eb211c80319c-00f067aa0ba902b7-01")
+	handler.ServeHTTP(httptest.NewRecorder(), r)
+
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+
+	if len(sampled) != 2 {
+		t.Fatalf("Metrics called %d times", len(sampled))
+	}
+	if !sampled[0] || !continued[0] {
+		t.Error("Sampled, continued request not reported correctly")
+	}
+	if sampled[1] || continued[1] {
+		t.Error("Unsampled, new request not reported correctly")
+	}
+}
+
+func TestMiddlewareOnError(t *testing.T) {
+	var errs []error
+	var received http.Header
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received = r.Header
+	})
+	handler := Middleware(next, MiddlewareConfig{
+		Member:   &TraceStateMember{Key: "Invalid Key"},
+		Sampling: SamplingBehaviorPassThrough,
+		OnError: func(r *http.Request, err error) {
+			errs = append(errs, err)
+		},
+	})
+
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Add(TraceParentHeader, "00-0af7651916cd43dd8448eb211c80319c-00f067aa0ba902b7-01")
+	handler.ServeHTTP(httptest.NewRecorder(), r)
+
+	if len(errs) != 1 {
+		t.Fatalf("OnError called %d times", len(errs))
+	}
+	if received.Get(TraceParentHeader) != "00-0af7651916cd43dd8448eb211c80319c-00f067aa0ba902b7-01" {
+		t.Error("Request not passed on unchanged")
+	}
+}
@@ -0,0 +1,37 @@
+package tracecontext
+
+// Parser parses trace context headers like ParseTraceContext while reusing
+// scratch buffers across calls and avoiding the regex overhead for the common
+// traceparent version. A Parser is not safe for concurrent use; pool it, e.g.
+// with sync.Pool, to use it on hot paths.
+type Parser struct {
+	candidates []string
+	members    []TraceStateMember
+}
+
+// Parse parses the provided traceparent and tracestate header values with
+// the same results as ParseTraceContext. The tracestate members of the
+// returned TraceContext are stored in a buffer owned by the Parser, so they
+// are only valid until the next call to Parse. Copy them, e.g. with Freeze, to
+// retain them longer.
+func (p *Parser) Parse(traceparent string, tracestate string) (*TraceContext, error) {
+	traceParent, ok, err := parseTraceParentVersionZero(traceparent)
+	if !ok {
+		traceParent, err = ParseTraceParent(traceparent)
+	}
+	// If the vendor failed to parse traceparent, it MUST NOT attempt to parse tracestate
+	if err != nil {
+		return nil, err
+	}
+	traceContext := TraceContext{
+		TraceParent: traceParent,
+	}
+
+	traceState, err := parseTraceState(tracestate, &p.candidates, &p.members, 0)
+	//failure to parse tracestate MUST NOT affect the parsing of traceparent
+	if err == nil {
+		traceContext.TraceState = traceState
+	}
+
+	return &traceContext, nil
+}
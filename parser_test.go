@@ -0,0 +1,87 @@
+package tracecontext
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+)
+
+var parserInputs = [][2]string{
+	{"00-0af7651916cd43dd8448eb211c80319c-00f067aa0ba902b7-01", "vendor1=val1, vendor2=val2 "},
+	{"00-0af7651916cd43dd8448eb211c80319c-00f067aa0ba902b7-00", ""},
+	{"00-0af7651916cd43dd8448eb211c80319c-00f067aa0ba902b7-01", "vendor1=val1,,Vendor2=val2"},
+	{"00-0af7651916cd43dd8448eb211c80319c-00f067aa0ba902b7-01", "t1@vendor1=val 1,\tvendor2=val2\t"},
+	{"00-0af7651916cd43dd8448eb211c80319c-00f067aa0ba902b7-01", "vendor1=val1 ,vendor2=val=2"},
+	{"01-0af7651916cd43dd8448eb211c80319c-00f067aa0ba902b7-01-123", "vendor1=val1"},
+	{"02-0af7651916cd43dd8448eb211c80319c-00f067aa0ba902b7-01", "vendor1=val1"},
+	{"ff-0af7651916cd43dd8448eb211c80319c-00f067aa0ba902b7-01", "vendor1=val1"},
+	{"00-00000000000000000000000000000000-00f067aa0ba902b7-01", "vendor1=val1"},
+	{"01-illegal", "vendor1=val1"},
+	{"", ""},
+}
+
+func TestParserParse(t *testing.T) {
+	p := Parser{}
+
+	for _, input := range parserInputs {
+		headers := http.Header{}
+		headers.Set(TraceParentHeader, input[0])
+		headers.Set(TraceStateHeader, input[1])
+		expected, expectedErr := ParseTraceContext(headers)
+
+		tc, err := p.Parse(input[0], input[1])
+
+		if (err == nil) != (expectedErr == nil) {
+			t.Errorf("Error mismatch for %v: %v, %v", input, err, expectedErr)
+			continue
+		}
+		if err != nil {
+			continue
+		}
+		if *tc.TraceParent != *expected.TraceParent {
+			t.Errorf("traceparent mismatch for %v", input)
+		}
+		if (tc.TraceState == nil) != (expected.TraceState == nil) ||
+			tc.TraceState != nil && tc.TraceState.String() != expected.TraceState.String() {
+			t.Errorf("tracestate mismatch for %v", input)
+		}
+	}
+}
+
+func TestParserParseReusesMembers(t *testing.T) {
+	p := Parser{}
+
+	tc1, _ := p.Parse("00-0af7651916cd43dd8448eb211c80319c-00f067aa0ba902b7-01", "vendor1=val1")
+	frozen := tc1.Freeze()
+	p.Parse("00-0af7651916cd43dd8448eb211c80319c-00f067aa0ba902b7-01", "vendor2=val2")
+
+	if frozen.TraceState() != "vendor1=val1" {
+		t.Error("Frozen result was modified")
+	}
+	if cap(p.members) != MaxMembers {
+		t.Error("Unexpected member buffer capacity:", cap(p.members))
+	}
+
+	p.Parse("00-0af7651916cd43dd8448eb211c80319c-00f067aa0ba902b7-01", strings.Repeat("vendor=val,", MaxMembers+1))
+	if cap(p.members) != MaxMembers {
+		t.Error("Member buffer grew beyond the member limit:", cap(p.members))
+	}
+}
+
+func BenchmarkParseTraceContext(b *testing.B) {
+	headers := http.Header{}
+	headers.Set(TraceParentHeader, "00-0af7651916cd43dd8448eb211c80319c-00f067aa0ba902b7-01")
+	headers.Set(TraceStateHeader, "vendor1=val1,vendor2=val2,vendor3=val3")
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		ParseTraceContext(headers)
+	}
+}
+
+func BenchmarkParserParse(b *testing.B) {
+	p := Parser{}
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		p.Parse("00-0af7651916cd43dd8448eb211c80319c-00f067aa0ba902b7-01", "vendor1=val1,vendor2=val2,vendor3=val3")
+	}
+}
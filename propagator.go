@@ -0,0 +1,197 @@
+package tracecontext
+
+import (
+	"errors"
+	"net/http"
+)
+
+// Propagator holds configuration that is applied when mutating and writing
+// trace context on behalf of a deployment.
+type Propagator struct {
+	// AllowedKeys restricts the tracestate keys that may be emitted. If it is
+	// empty, all keys are allowed.
+	AllowedKeys []string
+	// DeniedKeys lists tracestate keys that must never be emitted, e.g. keys
+	// of vendors the deployment doesn't own.
+	DeniedKeys []string
+	// LegacyTraceParentHeaders lists header names carrying a traceparent value
+	// in the standard format, e.g. "elastic-apm-traceparent". Parse falls back
+	// to them in order if the traceparent header is absent.
+	LegacyTraceParentHeaders []string
+	// MirrorLegacyHeaders causes WriteHeaders to also write the traceparent
+	// to all LegacyTraceParentHeaders.
+	MirrorLegacyHeaders bool
+	// DefaultSampling is the sampling behavior applied by Generate
+	DefaultSampling SamplingBehavior
+	// ErrorOnEmptyParentID makes the Propagator's Mutate, Generate and Handle
+	// methods return ErrEmptyParentID for an empty parentId instead of
+	// generating a random one. This helps to catch programming mistakes in
+	// callers that always intend to provide their own span id. It doesn't
+	// affect the package-level GenerateTraceContext, HandleTraceContext and
+	// TraceContext.Mutate, which still generate a random parentId.
+	ErrorOnEmptyParentID bool
+
+	required *TraceStateMember
+}
+
+// ErrEmptyParentID is returned by the Propagator methods for an empty parentId
+// if ErrorOnEmptyParentID is enabled
+var ErrEmptyParentID = errors.New("empty parent id")
+
+// checkParentId returns ErrEmptyParentID if the parentId is empty and
+// ErrorOnEmptyParentID is enabled
+func (p *Propagator) checkParentId(parentId string) error {
+	if p.ErrorOnEmptyParentID && parentId == "" {
+		return ErrEmptyParentID
+	}
+	return nil
+}
+
+// RequireVendor makes Generate and Handle guarantee that every resulting
+// TraceContext carries a tracestate member with the provided key. If the key
+// is absent, the member is added with the provided value.
+func (p *Propagator) RequireVendor(key, value string) {
+	p.required = &TraceStateMember{Key: key, Value: value}
+}
+
+// ensureRequired adds the member configured with RequireVendor to the
+// TraceContext if its key is absent
+func (p *Propagator) ensureRequired(tc *TraceContext) error {
+	if p.required == nil {
+		return nil
+	}
+	return tc.EnsureState(*p.required)
+}
+
+// keyPermitted returns true if the provided tracestate key may be emitted
+// according to the allow and deny lists
+func (p *Propagator) keyPermitted(key string) bool {
+	for _, k := range p.DeniedKeys {
+		if k == key {
+			return false
+		}
+	}
+	if len(p.AllowedKeys) == 0 {
+		return true
+	}
+	for _, k := range p.AllowedKeys {
+		if k == key {
+			return true
+		}
+	}
+	return false
+}
+
+// Parse extracts the TraceContext information from the headers like
+// ParseTraceContext. If the traceparent header is absent, the first present
+// header of LegacyTraceParentHeaders is used instead.
+func (p *Propagator) Parse(headers http.Header) (*TraceContext, error) {
+	if headers.Get(TraceParentHeader) == "" {
+		for _, name := range p.LegacyTraceParentHeaders {
+			value := headers.Get(name)
+			if value == "" {
+				continue
+			}
+			// Don't modify the caller's headers
+			headers = headers.Clone()
+			headers.Set(TraceParentHeader, value)
+			break
+		}
+	}
+
+	return ParseTraceContext(headers)
+}
+
+// Mutate mutates the TraceContext like TraceContext.Mutate, but rejects
+// members whose key is not permitted by the propagator configuration.
+func (p *Propagator) Mutate(tc *TraceContext, parentId string, sampling SamplingBehavior, member *TraceStateMember) error {
+	if member != nil && !p.keyPermitted(member.Key) {
+		return errors.New("tracestate key is not permitted")
+	}
+	err := p.checkParentId(parentId)
+	if err != nil {
+		return err
+	}
+	return tc.Mutate(parentId, sampling, member)
+}
+
+// Generate generates a new TraceContext like GenerateTraceContext using the
+// DefaultSampling behavior. The member configured with RequireVendor is added
+// if its key is absent. Members whose key is not permitted by the
+// propagator configuration are rejected.
+func (p *Propagator) Generate(parentId string, member *TraceStateMember) (*TraceContext, error) {
+	if member != nil && !p.keyPermitted(member.Key) {
+		return nil, errors.New("tracestate key is not permitted")
+	}
+	err := p.checkParentId(parentId)
+	if err != nil {
+		return nil, err
+	}
+
+	tc, err := GenerateTraceContext(parentId, member, p.DefaultSampling)
+	if err != nil {
+		return nil, err
+	}
+
+	err = p.ensureRequired(tc)
+	if err != nil {
+		return nil, err
+	}
+	return tc, nil
+}
+
+// Handle handles the trace context read from the input headers like
+// HandleTraceContext. The member configured with RequireVendor is added if its
+// key is absent. Members whose key is not permitted by the propagator
+// configuration are rejected and the returned headers are written like
+// Propagator.WriteHeaders.
+func (p *Propagator) Handle(headers *http.Header, parentId string, member *TraceStateMember, sampling SamplingBehavior) (*http.Header, *TraceContext, error) {
+	if member != nil && !p.keyPermitted(member.Key) {
+		return nil, nil, errors.New("tracestate key is not permitted")
+	}
+	err := p.checkParentId(parentId)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	newHeaders, tc, err := HandleTraceContext(headers, parentId, member, sampling)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	err = p.ensureRequired(tc)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	p.WriteHeaders(tc, newHeaders)
+	return newHeaders, tc, nil
+}
+
+// WriteHeaders writes the traceparent and tracestate headers like
+// TraceContext.WriteHeaders. Tracestate members whose key is not permitted by
+// the propagator configuration are stripped from the written header. The
+// TraceContext itself is not modified.
+func (p *Propagator) WriteHeaders(tc *TraceContext, headers *http.Header) {
+	filtered := TraceContext{
+		TraceParent: tc.TraceParent,
+	}
+	if tc.TraceState != nil {
+		filtered.TraceState = NewEmptyTraceState()
+		for _, m := range tc.TraceState.Members {
+			if p.keyPermitted(m.Key) {
+				filtered.TraceState.Members = append(filtered.TraceState.Members, m)
+			}
+		}
+	}
+
+	// Strip denied keys that may already be present in the headers
+	headers.Del(TraceStateHeader)
+	filtered.WriteHeaders(headers)
+
+	if p.MirrorLegacyHeaders && tc.TraceParent != nil {
+		for _, name := range p.LegacyTraceParentHeaders {
+			headers.Set(name, tc.TraceParent.String())
+		}
+	}
+}
@@ -0,0 +1,210 @@
+package tracecontext
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestPropagatorWriteHeadersDeniedKey(t *testing.T) {
+	p := Propagator{DeniedKeys: []string{"competitor"}}
+	ts, _ := ParseTraceState("vendor1=val1,competitor=val2")
+	tp, _ := ParseTraceParent("00-0af7651916cd43dd8448eb211c80319c-00f067aa0ba902b7-01")
+	tc := TraceContext{TraceParent: tp, TraceState: ts}
+
+	headers := http.Header{}
+	p.WriteHeaders(&tc, &headers)
+
+	if headers.Get(TraceStateHeader) != "vendor1=val1" {
+		t.Errorf("Denied key not stripped: '%s'", headers.Get(TraceStateHeader))
+	}
+	if len(tc.TraceState.Members) != 2 {
+		t.Error("TraceContext was modified")
+	}
+}
+
+func TestPropagatorWriteHeadersAllowedKeys(t *testing.T) {
+	p := Propagator{AllowedKeys: []string{"vendor1"}}
+	ts, _ := ParseTraceState("vendor1=val1,vendor2=val2")
+	tc := TraceContext{TraceState: ts}
+
+	headers := http.Header{}
+	p.WriteHeaders(&tc, &headers)
+
+	if headers.Get(TraceStateHeader) != "vendor1=val1" {
+		t.Errorf("Key not on allowlist not stripped: '%s'", headers.Get(TraceStateHeader))
+	}
+}
+
+func TestPropagatorMutateDeniedKey(t *testing.T) {
+	p := Propagator{DeniedKeys: []string{"competitor"}}
+	tc, _ := NewTraceContext("0af7651916cd43dd8448eb211c80319c", "00f067aa0ba902b7")
+
+	err := p.Mutate(tc, "", SamplingBehaviorPassThrough, &TraceStateMember{Key: "competitor", Value: "val"})
+
+	if err == nil {
+		t.Error("Denied key didn't cause an error")
+	}
+	if len(tc.TraceState.Members) != 0 {
+		t.Error("Denied key was added")
+	}
+}
+
+func TestPropagatorParseLegacyHeader(t *testing.T) {
+	p := Propagator{LegacyTraceParentHeaders: []string{"elastic-apm-traceparent"}}
+	headers := http.Header{}
+	headers.Set("elastic-apm-traceparent", "00-0af7651916cd43dd8448eb211c80319c-00f067aa0ba902b7-01")
+
+	tc, err := p.Parse(headers)
+
+	if err != nil {
+		t.Error("Failed to parse legacy header:", err)
+	}
+	if tc.TraceParent.String() != "00-0af7651916cd43dd8448eb211c80319c-00f067aa0ba902b7-01" {
+		t.Error("traceparent not parsed correctly")
+	}
+	if headers.Get(TraceParentHeader) != "" {
+		t.Error("Headers were modified")
+	}
+}
+
+func TestPropagatorParsePrefersStandardHeader(t *testing.T) {
+	p := Propagator{LegacyTraceParentHeaders: []string{"elastic-apm-traceparent"}}
+	headers := http.Header{}
+	headers.Set(TraceParentHeader, "00-0af7651916cd43dd8448eb211c80319c-00f067aa0ba902b7-01")
+	headers.Set("elastic-apm-traceparent", "00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01")
+
+	tc, _ := p.Parse(headers)
+
+	if tc.TraceParent.ParentId() != "00f067aa0ba902b7" {
+		t.Error("Legacy header preferred over the standard header")
+	}
+}
+
+func TestPropagatorWriteHeadersMirrorLegacy(t *testing.T) {
+	p := Propagator{
+		LegacyTraceParentHeaders: []string{"elastic-apm-traceparent"},
+		MirrorLegacyHeaders:      true,
+	}
+	tc, _ := NewTraceContext("0af7651916cd43dd8448eb211c80319c", "00f067aa0ba902b7")
+
+	headers := http.Header{}
+	p.WriteHeaders(tc, &headers)
+
+	if headers.Get("elastic-apm-traceparent") != headers.Get(TraceParentHeader) {
+		t.Errorf("Legacy header not mirrored: '%s'", headers.Get("elastic-apm-traceparent"))
+	}
+}
+
+func TestPropagatorGenerateDefaultSampling(t *testing.T) {
+	p := Propagator{DefaultSampling: SamplingBehaviorAlwaysSampled}
+
+	tc, err := p.Generate("", &TraceStateMember{Key: "vendor1"})
+
+	if err != nil {
+		t.Error("Failed to generate trace context:", err)
+	}
+	if !tc.TraceParent.IsSampled() {
+		t.Error("Default sampling behavior not applied")
+	}
+	if tc.TraceState.MemberValue("vendor1") != tc.TraceParent.ParentId() {
+		t.Error("Member not added")
+	}
+}
+
+func TestPropagatorGenerateDeniedKey(t *testing.T) {
+	p := Propagator{DeniedKeys: []string{"competitor"}}
+
+	_, err := p.Generate("", &TraceStateMember{Key: "competitor", Value: "val1"})
+
+	if err == nil {
+		t.Error("Denied key accepted")
+	}
+}
+
+func TestPropagatorRequireVendorGenerate(t *testing.T) {
+	p := Propagator{}
+	p.RequireVendor("acme", "val1")
+
+	tc, err := p.Generate("", nil)
+
+	if err != nil {
+		t.Error("Failed to generate trace context:", err)
+	}
+	if tc.TraceState.String() != "acme=val1" {
+		t.Errorf("Required vendor entry missing: '%s'", tc.TraceState.String())
+	}
+}
+
+func TestPropagatorRequireVendorHandle(t *testing.T) {
+	p := Propagator{}
+	p.RequireVendor("acme", "val1")
+	headers := http.Header{}
+	headers.Set(TraceParentHeader, "00-0af7651916cd43dd8448eb211c80319c-00f067aa0ba902b7-01")
+	headers.Set(TraceStateHeader, "vendor1=val1")
+
+	newHeaders, tc, err := p.Handle(&headers, "", nil, SamplingBehaviorPassThrough)
+
+	if err != nil {
+		t.Error("Failed to handle trace context:", err)
+	}
+	if v, _ := tc.OwnState("acme"); v != "val1" {
+		t.Error("Required vendor entry missing")
+	}
+	if newHeaders.Get(TraceStateHeader) != "acme=val1,vendor1=val1" {
+		t.Errorf("Wrong tracestate header written: '%s'", newHeaders.Get(TraceStateHeader))
+	}
+}
+
+func TestPropagatorRequireVendorPresent(t *testing.T) {
+	p := Propagator{}
+	p.RequireVendor("acme", "val1")
+	headers := http.Header{}
+	headers.Set(TraceParentHeader, "00-0af7651916cd43dd8448eb211c80319c-00f067aa0ba902b7-01")
+	headers.Set(TraceStateHeader, "vendor1=val1,acme=val2")
+
+	newHeaders, _, _ := p.Handle(&headers, "", nil, SamplingBehaviorPassThrough)
+
+	if newHeaders.Get(TraceStateHeader) != "vendor1=val1,acme=val2" {
+		t.Errorf("Present vendor entry modified: '%s'", newHeaders.Get(TraceStateHeader))
+	}
+}
+
+func TestPropagatorEmptyParentIDLenient(t *testing.T) {
+	p := Propagator{}
+	headers := http.Header{}
+	headers.Set(TraceParentHeader, "00-0af7651916cd43dd8448eb211c80319c-00f067aa0ba902b7-01")
+
+	newHeaders, tc, err := p.Handle(&headers, "", nil, SamplingBehaviorPassThrough)
+
+	if err != nil {
+		t.Error("Failed to handle trace context:", err)
+	}
+	if tc.TraceParent.ParentId() == "00f067aa0ba902b7" || newHeaders.Get(TraceParentHeader) != tc.TraceParent.String() {
+		t.Error("parent id not generated")
+	}
+}
+
+func TestPropagatorErrorOnEmptyParentID(t *testing.T) {
+	p := Propagator{ErrorOnEmptyParentID: true}
+	headers := http.Header{}
+	headers.Set(TraceParentHeader, "00-0af7651916cd43dd8448eb211c80319c-00f067aa0ba902b7-01")
+
+	if _, _, err := p.Handle(&headers, "", nil, SamplingBehaviorPassThrough); err != ErrEmptyParentID {
+		t.Errorf("Wrong error for handling with an empty parent id: %v", err)
+	}
+	if _, err := p.Generate("", nil); err != ErrEmptyParentID {
+		t.Errorf("Wrong error for generating with an empty parent id: %v", err)
+	}
+
+	tc, _ := NewTraceContext("0af7651916cd43dd8448eb211c80319c", "00f067aa0ba902b7")
+	if err := p.Mutate(tc, "", SamplingBehaviorPassThrough, nil); err != ErrEmptyParentID {
+		t.Errorf("Wrong error for mutating with an empty parent id: %v", err)
+	}
+	if tc.TraceParent.ParentId() != "00f067aa0ba902b7" {
+		t.Error("parent id was changed")
+	}
+
+	if _, _, err := p.Handle(&headers, "b7ad6b7169203331", nil, SamplingBehaviorPassThrough); err != nil {
+		t.Error("Failed to handle trace context with a parent id:", err)
+	}
+}
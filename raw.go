@@ -0,0 +1,38 @@
+package tracecontext
+
+import (
+	"bufio"
+	"net/http"
+	"net/textproto"
+	"strings"
+)
+
+// ParseTraceContextRaw attempts to extract TraceContext information from a
+// raw HTTP header block like "traceparent: ...\r\ntracestate: ...\r\n".
+// Header names are matched case-insensitively and continuation lines are
+// supported. It behaves like ParseTraceContext otherwise.
+func ParseTraceContextRaw(block string) (*TraceContext, error) {
+	// The reader expects the block to be terminated by a blank line
+	block = strings.TrimRight(block, "\r\n") + "\r\n\r\n"
+	r := textproto.NewReader(bufio.NewReader(strings.NewReader(block)))
+
+	headers, err := r.ReadMIMEHeader()
+	if err != nil {
+		return nil, err
+	}
+
+	return ParseTraceContext(http.Header(headers))
+}
+
+// ScanTraceContext reads a header block from the reader up to and including
+// the terminating blank line and extracts the TraceContext information from
+// it. The reader is left positioned after the blank line, so that streaming
+// proxies can continue to read the body.
+func ScanTraceContext(r *bufio.Reader) (*TraceContext, error) {
+	headers, err := textproto.NewReader(r).ReadMIMEHeader()
+	if err != nil {
+		return nil, err
+	}
+
+	return ParseTraceContext(http.Header(headers))
+}
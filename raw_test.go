@@ -0,0 +1,87 @@
+package tracecontext
+
+import (
+	"bufio"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestParseTraceContextRaw(t *testing.T) {
+	block := "Host: example.com\r\n" +
+		"TraceParent: 00-0af7651916cd43dd8448eb211c80319c-00f067aa0ba902b7-01\r\n" +
+		"TRACESTATE: vendor1=val1,\r\n" +
+		" vendor2=val2\r\n"
+
+	tc, err := ParseTraceContextRaw(block)
+
+	if err != nil {
+		t.Error("Failed to parse raw header block:", err)
+	}
+	if tc.TraceParent.String() != "00-0af7651916cd43dd8448eb211c80319c-00f067aa0ba902b7-01" {
+		t.Error("traceparent not parsed correctly")
+	}
+	if tc.TraceState == nil || tc.TraceState.String() != "vendor1=val1,vendor2=val2" {
+		t.Error("tracestate not parsed correctly")
+	}
+}
+
+func TestParseTraceContextRawInvalid(t *testing.T) {
+	_, err := ParseTraceContextRaw("tracestate: vendor1=val1\r\n")
+
+	if err == nil {
+		t.Error("Header block without traceparent parsed")
+	}
+}
+
+func TestParseTraceContextRawTraceStateFirst(t *testing.T) {
+	block := "tracestate: vendor1=val1\r\n" +
+		"traceparent: 00-0af7651916cd43dd8448eb211c80319c-00f067aa0ba902b7-01\r\n"
+
+	tc, err := ParseTraceContextRaw(block)
+
+	if err != nil {
+		t.Error("Failed to parse raw header block:", err)
+	}
+	if tc.TraceParent.String() != "00-0af7651916cd43dd8448eb211c80319c-00f067aa0ba902b7-01" {
+		t.Error("traceparent not parsed correctly")
+	}
+	if tc.TraceState == nil || tc.TraceState.String() != "vendor1=val1" {
+		t.Error("tracestate not parsed correctly")
+	}
+}
+
+func TestScanTraceContext(t *testing.T) {
+	stream := "Host: example.com\r\n" +
+		"traceparent: 00-0af7651916cd43dd8448eb211c80319c-00f067aa0ba902b7-01\r\n" +
+		"tracestate: vendor1=val1\r\n" +
+		"\r\n" +
+		"body"
+	r := bufio.NewReader(strings.NewReader(stream))
+
+	tc, err := ScanTraceContext(r)
+
+	if err != nil {
+		t.Error("Failed to scan header block:", err)
+	}
+	if tc.TraceParent.String() != "00-0af7651916cd43dd8448eb211c80319c-00f067aa0ba902b7-01" {
+		t.Error("traceparent not parsed correctly")
+	}
+	if tc.TraceState == nil || tc.TraceState.String() != "vendor1=val1" {
+		t.Error("tracestate not parsed correctly")
+	}
+	body, _ := io.ReadAll(r)
+	if string(body) != "body" {
+		t.Errorf("Reader not positioned after the header block: '%s'", body)
+	}
+}
+
+func TestScanTraceContextMissingTraceParent(t *testing.T) {
+	r := bufio.NewReader(strings.NewReader("Host: example.com\r\n\r\n"))
+
+	_, err := ScanTraceContext(r)
+
+	if err == nil {
+		t.Error("Header block without traceparent parsed")
+	}
+}
@@ -0,0 +1,158 @@
+package tracecontext
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// OTelTraceStateKey is the tracestate key of the OpenTelemetry entry, which
+// carries sub-fields like the explicit randomness value "rv"
+const OTelTraceStateKey = "ot"
+
+// Randomness returns the randomness value used for consistent sampling. If the
+// random flag is set, it is taken from the trace id. Otherwise the explicit
+// randomness value in the "rv" sub-field of the OpenTelemetry tracestate entry
+// is used, which must consist of 14 lowercase hex characters. The boolean is
+// false if neither source is available.
+func (tc *TraceContext) Randomness() (uint64, bool) {
+	if tc.TraceParent == nil {
+		return 0, false
+	}
+	if tc.TraceParent.IsRandom() {
+		return tc.TraceParent.RandomnessValue(), true
+	}
+
+	if tc.TraceState == nil {
+		return 0, false
+	}
+	for _, m := range tc.TraceState.Members {
+		if m.Key != OTelTraceStateKey {
+			continue
+		}
+		rv, ok := m.SubValue("rv")
+		if !ok || len(rv) != 14 || !isLowerHex(rv) {
+			return 0, false
+		}
+		r, err := strconv.ParseUint(rv, 16, 64)
+		return r, err == nil
+	}
+	return 0, false
+}
+
+// ConsistentSampling returns the sampling behavior for consistent probability
+// sampling with the provided 56 bit rejection threshold: the trace is sampled
+// if its randomness value is at least the threshold. If no randomness value is
+// available (see Randomness), SamplingBehaviorPassThrough is returned so that
+// the decision of the caller is kept.
+func (tc *TraceContext) ConsistentSampling(threshold uint64) SamplingBehavior {
+	r, ok := tc.Randomness()
+	if !ok {
+		return SamplingBehaviorPassThrough
+	}
+	if r >= threshold {
+		return SamplingBehaviorAlwaysSampled
+	}
+	return SamplingBehaviorNeverSampled
+}
+
+// SamplingDecider returns the sampling behavior for a TraceContext, which can
+// be passed on to Mutate
+type SamplingDecider func(tc *TraceContext) SamplingBehavior
+
+// SampleOnlyTraceIDs returns a SamplingDecider that samples trace contexts
+// whose trace id is one of the provided ids and never samples others, e.g. to
+// force-sample a single trace while debugging
+func SampleOnlyTraceIDs(ids ...string) SamplingDecider {
+	set := make(map[string]struct{}, len(ids))
+	for _, id := range ids {
+		set[id] = struct{}{}
+	}
+
+	return func(tc *TraceContext) SamplingBehavior {
+		if tc.TraceParent == nil {
+			return SamplingBehaviorNeverSampled
+		}
+		if _, ok := set[tc.TraceParent.traceId]; ok {
+			return SamplingBehaviorAlwaysSampled
+		}
+		return SamplingBehaviorNeverSampled
+	}
+}
+
+// ReconcileSampling updates the sampled flag with the sampling decision the
+// vendor recorded in its tracestate member, which is considered more
+// authoritative than the flag. The decision is read from the "s" sub-field of
+// the member value, e.g. "s:1" for sampled and "s:0" for not sampled. The flag
+// is left unchanged if no decision was recorded.
+func (tc *TraceContext) ReconcileSampling(vendorKey string) {
+	if tc.TraceParent == nil || tc.TraceState == nil {
+		return
+	}
+	member := TraceStateMember{Key: vendorKey, Value: tc.TraceState.MemberValue(vendorKey)}
+	decision, _ := member.SubValue("s")
+	switch decision {
+	case "1":
+		tc.TraceParent.SetSampled(true)
+	case "0":
+		tc.TraceParent.SetSampled(false)
+	}
+}
+
+// maxRandomness is the exclusive upper bound of 56 bit sampling thresholds and
+// randomness values
+const maxRandomness = 1 << 56
+
+// SetOTelSampling records the 56 bit rejection threshold and randomness value
+// in the "th" and "rv" sub-fields of the OpenTelemetry tracestate entry, e.g.
+// "ot=th:8;rv:9b8233f7e3a151". Other sub-fields of an existing entry are kept.
+// As the entry is modified, it is moved to the beginning of the list.
+func (tc *TraceContext) SetOTelSampling(threshold, randomness uint64) error {
+	if threshold >= maxRandomness || randomness >= maxRandomness {
+		return errors.New("threshold and randomness must be 56 bit values")
+	}
+
+	// Trailing zeros of the threshold are omitted
+	th := strings.TrimRight(fmt.Sprintf("%014x", threshold), "0")
+	if th == "" {
+		th = "0"
+	}
+
+	member := TraceStateMember{Key: OTelTraceStateKey}
+	if tc.TraceState != nil {
+		member.Value = tc.TraceState.MemberValue(OTelTraceStateKey)
+	}
+	err := member.SetSubValue("th", th)
+	if err != nil {
+		return err
+	}
+	err = member.SetSubValue("rv", fmt.Sprintf("%014x", randomness))
+	if err != nil {
+		return err
+	}
+
+	if tc.TraceState == nil {
+		tc.TraceState = NewEmptyTraceState()
+	}
+	return tc.TraceState.Mutate(member)
+}
+
+// ForcedSampleMarker is the tracestate member that marks a trace as forced to
+// be sampled, e.g. for debugging. If its Value is empty, the presence of the
+// key is sufficient. IsForcedSample always returns false while the Key is
+// empty, which is the default.
+var ForcedSampleMarker = TraceStateMember{}
+
+// IsForcedSample returns true if the tracestate contains the
+// ForcedSampleMarker
+func (tc *TraceContext) IsForcedSample() bool {
+	if ForcedSampleMarker.Key == "" || tc.TraceState == nil {
+		return false
+	}
+	value := tc.TraceState.MemberValue(ForcedSampleMarker.Key)
+	if value == "" {
+		return false
+	}
+	return ForcedSampleMarker.Value == "" || value == ForcedSampleMarker.Value
+}
@@ -0,0 +1,173 @@
+package tracecontext
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestConsistentSamplingRandomFlag(t *testing.T) {
+	headers := http.Header{}
+	headers.Add(TraceParentHeader, "00-0af7651916cd43dd8448eb211c80319c-00f067aa0ba902b7-02")
+	headers.Add(TraceStateHeader, "ot=rv:00000000000000")
+	tc, _ := ParseTraceContext(headers)
+
+	if r, ok := tc.Randomness(); !ok || r != 0x48eb211c80319c {
+		t.Errorf("Wrong randomness value %x", r)
+	}
+	if s := tc.ConsistentSampling(0x40000000000000); s != SamplingBehaviorAlwaysSampled {
+		t.Errorf("Wrong sampling behavior %d", s)
+	}
+	if s := tc.ConsistentSampling(0x50000000000000); s != SamplingBehaviorNeverSampled {
+		t.Errorf("Wrong sampling behavior %d", s)
+	}
+}
+
+func TestConsistentSamplingExplicitRandomness(t *testing.T) {
+	headers := http.Header{}
+	headers.Add(TraceParentHeader, "00-0af7651916cd43dd8448eb211c80319c-00f067aa0ba902b7-00")
+	headers.Add(TraceStateHeader, "vendor1=val1,ot=th:8;rv:10000000000000")
+	tc, _ := ParseTraceContext(headers)
+
+	if r, ok := tc.Randomness(); !ok || r != 0x10000000000000 {
+		t.Errorf("Wrong randomness value %x", r)
+	}
+	// The trace id alone would lead to the sampled decision
+	if s := tc.ConsistentSampling(0x40000000000000); s != SamplingBehaviorNeverSampled {
+		t.Errorf("Wrong sampling behavior %d", s)
+	}
+}
+
+func TestConsistentSamplingNoRandomness(t *testing.T) {
+	headers := http.Header{}
+	headers.Add(TraceParentHeader, "00-0af7651916cd43dd8448eb211c80319c-00f067aa0ba902b7-00")
+	headers.Add(TraceStateHeader, "ot=th:8;rv:invalid")
+	tc, _ := ParseTraceContext(headers)
+
+	if _, ok := tc.Randomness(); ok {
+		t.Error("Invalid randomness value accepted")
+	}
+	if s := tc.ConsistentSampling(0); s != SamplingBehaviorPassThrough {
+		t.Errorf("Wrong sampling behavior %d", s)
+	}
+}
+
+func TestSampleOnlyTraceIDs(t *testing.T) {
+	decide := SampleOnlyTraceIDs("0af7651916cd43dd8448eb211c80319c", "4bf92f3577b34da6a3ce929d0e0e4736")
+
+	matching, _ := NewTraceContext("0af7651916cd43dd8448eb211c80319c", "00f067aa0ba902b7")
+	if s := decide(matching); s != SamplingBehaviorAlwaysSampled {
+		t.Errorf("Wrong sampling behavior for matching trace id %d", s)
+	}
+
+	other, _ := NewTraceContext("0af7651916cd43dd8448eb211c80319d", "00f067aa0ba902b7")
+	if s := decide(other); s != SamplingBehaviorNeverSampled {
+		t.Errorf("Wrong sampling behavior for other trace id %d", s)
+	}
+
+	if s := decide(&TraceContext{}); s != SamplingBehaviorNeverSampled {
+		t.Errorf("Wrong sampling behavior without TraceParent %d", s)
+	}
+}
+
+func TestReconcileSampling(t *testing.T) {
+	headers := http.Header{}
+	headers.Add(TraceParentHeader, "00-0af7651916cd43dd8448eb211c80319c-00f067aa0ba902b7-00")
+	headers.Add(TraceStateHeader, "vendor1=s:1;p:00f067aa0ba902b7,vendor2=s:0")
+	tc, _ := ParseTraceContext(headers)
+
+	tc.ReconcileSampling("vendor1")
+	if !tc.TraceParent.IsSampled() {
+		t.Error("Recorded sampled decision not applied")
+	}
+
+	tc.ReconcileSampling("vendor2")
+	if tc.TraceParent.IsSampled() {
+		t.Error("Recorded unsampled decision not applied")
+	}
+}
+
+func TestReconcileSamplingNoDecision(t *testing.T) {
+	headers := http.Header{}
+	headers.Add(TraceParentHeader, "00-0af7651916cd43dd8448eb211c80319c-00f067aa0ba902b7-01")
+	headers.Add(TraceStateHeader, "vendor1=p:00f067aa0ba902b7")
+	tc, _ := ParseTraceContext(headers)
+
+	tc.ReconcileSampling("vendor1")
+	tc.ReconcileSampling("vendor2")
+
+	if !tc.TraceParent.IsSampled() {
+		t.Error("Sampled flag changed without recorded decision")
+	}
+}
+
+func TestSetOTelSampling(t *testing.T) {
+	headers := http.Header{}
+	headers.Add(TraceParentHeader, "00-0af7651916cd43dd8448eb211c80319c-00f067aa0ba902b7-01")
+	headers.Add(TraceStateHeader, "vendor1=val1,ot=p:8;th:c")
+	tc, _ := ParseTraceContext(headers)
+
+	err := tc.SetOTelSampling(0x80000000000000, 0x9b8233f7e3a151)
+
+	if err != nil {
+		t.Error("Failed to set OTel sampling:", err)
+	}
+	if s := tc.TraceState.String(); s != "ot=p:8;th:8;rv:9b8233f7e3a151,vendor1=val1" {
+		t.Errorf("Wrong tracestate: '%s'", s)
+	}
+	if r, _ := tc.Randomness(); r != 0x9b8233f7e3a151 {
+		t.Errorf("Randomness value not readable: %x", r)
+	}
+}
+
+func TestSetOTelSamplingZeroThreshold(t *testing.T) {
+	tc, _ := NewTraceContext("0af7651916cd43dd8448eb211c80319c", "00f067aa0ba902b7")
+
+	tc.SetOTelSampling(0, 1)
+
+	if v := tc.TraceState.MemberValue(OTelTraceStateKey); v != "th:0;rv:00000000000001" {
+		t.Errorf("Wrong ot value: '%s'", v)
+	}
+	if err := tc.SetOTelSampling(1<<56, 0); err == nil {
+		t.Error("Threshold exceeding 56 bits accepted")
+	}
+}
+
+func TestIsForcedSample(t *testing.T) {
+	ForcedSampleMarker = TraceStateMember{Key: "debug", Value: "1"}
+	defer func() { ForcedSampleMarker = TraceStateMember{} }()
+
+	headers := http.Header{}
+	headers.Add(TraceParentHeader, "00-0af7651916cd43dd8448eb211c80319c-00f067aa0ba902b7-00")
+	headers.Add(TraceStateHeader, "vendor1=val1,debug=1")
+	tc, _ := ParseTraceContext(headers)
+
+	if !tc.IsForcedSample() {
+		t.Error("Forced sample marker not detected")
+	}
+
+	ForcedSampleMarker.Value = ""
+	if !tc.IsForcedSample() {
+		t.Error("Forced sample marker key not detected")
+	}
+}
+
+func TestIsForcedSampleAbsent(t *testing.T) {
+	headers := http.Header{}
+	headers.Add(TraceParentHeader, "00-0af7651916cd43dd8448eb211c80319c-00f067aa0ba902b7-00")
+	headers.Add(TraceStateHeader, "vendor1=val1,debug=0")
+	tc, _ := ParseTraceContext(headers)
+
+	if tc.IsForcedSample() {
+		t.Error("Forced sample detected without configured marker")
+	}
+
+	ForcedSampleMarker = TraceStateMember{Key: "debug", Value: "1"}
+	defer func() { ForcedSampleMarker = TraceStateMember{} }()
+
+	if tc.IsForcedSample() {
+		t.Error("Forced sample detected with other marker value")
+	}
+	if (&TraceContext{}).IsForcedSample() {
+		t.Error("Forced sample detected without tracestate")
+	}
+}
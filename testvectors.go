@@ -0,0 +1,37 @@
+package tracecontext
+
+// TestVector is a traceparent header value with its expected validity
+type TestVector struct {
+	Header string
+	Valid  bool
+	Reason string
+}
+
+// GenerateTestVectors returns valid and invalid traceparent header values
+// covering the edge cases documented in the specification, for conformance
+// testing against other implementations
+func GenerateTestVectors() []TestVector {
+	return []TestVector{
+		{"00-0af7651916cd43dd8448eb211c80319c-00f067aa0ba902b7-01", true, "sampled"},
+		{"00-0af7651916cd43dd8448eb211c80319c-00f067aa0ba902b7-00", true, "not sampled"},
+		{"00-0af7651916cd43dd8448eb211c80319c-00f067aa0ba902b7-ff", true, "unknown flags set"},
+		{"00-00000000000000000000000000000000-00f067aa0ba902b7-01", false, "all zero trace id"},
+		{"00-0af7651916cd43dd8448eb211c80319c-0000000000000000-01", false, "all zero parent id"},
+		{"ff-0af7651916cd43dd8448eb211c80319c-00f067aa0ba902b7-01", false, "version ff"},
+		{"ff-0af7651916cd43dd8448eb211c80319c-00f067aa0ba902b7-01-00", false, "version ff with additional field"},
+		{"01-0af7651916cd43dd8448eb211c80319c-00f067aa0ba902b7-01", true, "higher version"},
+		{"01-0af7651916cd43dd8448eb211c80319c-00f067aa0ba902b7-01-extra", true, "higher version with additional field"},
+		{"01-0af7651916cd43dd8448eb211c80319c-00f067aa0ba902b7-01extra", false, "higher version with flags not followed by dash"},
+		{"01-0af7651916cd43dd8448eb211c80319c-00f067aa0ba902b7", false, "higher version shorter than 55 characters"},
+		{"00-0af7651916cd43dd8448eb211c80319c-00f067aa0ba902b7-01-extra", false, "version 00 with additional field"},
+		{"00-0AF7651916CD43DD8448EB211C80319C-00F067AA0BA902B7-01", false, "uppercase hex"},
+		{"00_0af7651916cd43dd8448eb211c80319c_00f067aa0ba902b7_01", false, "wrong delimiter"},
+		{"00-0af7651916cd43dd8448eb211c80319c-00f067aa0ba902b7-1", false, "flags too short"},
+		{"00-0af7651916cd43dd8448eb211c8031-00f067aa0ba902b7-01", false, "trace id too short"},
+		{"00-0af7651916cd43dd8448eb211c80319c-00f067aa0ba902-01", false, "parent id too short"},
+		{"0g-0af7651916cd43dd8448eb211c80319c-00f067aa0ba902b7-01", false, "version not hex"},
+		{"00-0af7651916cd43dd8448eb211c80319g-00f067aa0ba902b7-01", false, "trace id not hex"},
+		{" 00-0af7651916cd43dd8448eb211c80319c-00f067aa0ba902b7-01", false, "leading whitespace"},
+		{"", false, "empty"},
+	}
+}
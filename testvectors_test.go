@@ -0,0 +1,15 @@
+package tracecontext
+
+import (
+	"testing"
+)
+
+func TestGenerateTestVectors(t *testing.T) {
+	for _, v := range GenerateTestVectors() {
+		_, err := ParseTraceParent(v.Header)
+
+		if (err == nil) != v.Valid {
+			t.Errorf("Test vector '%s' (%s) doesn't match parser behavior: %v", v.Header, v.Reason, err)
+		}
+	}
+}
@@ -0,0 +1,76 @@
+// Package tracecbor implements CBOR serialization of trace context for
+// compact caching across services.
+package tracecbor
+
+import (
+	"encoding/hex"
+	"errors"
+
+	"github.com/fxamacker/cbor/v2"
+	tracecontext "github.com/garciasdos/w3c-trace-context"
+)
+
+// traceContext is the CBOR representation of a TraceContext. Trace id and
+// span id are encoded as byte strings and the flags as an unsigned integer.
+type traceContext struct {
+	TraceId    []byte `cbor:"1,keyasint"`
+	SpanId     []byte `cbor:"2,keyasint"`
+	Flags      uint8  `cbor:"3,keyasint"`
+	TraceState string `cbor:"4,keyasint,omitempty"`
+}
+
+// Marshal returns the CBOR encoding of the TraceContext
+func Marshal(tc *tracecontext.TraceContext) ([]byte, error) {
+	if tc.TraceParent == nil {
+		return nil, errors.New("TraceContext without TraceParent cannot be marshaled")
+	}
+
+	traceId, err := hex.DecodeString(tc.TraceParent.TraceId())
+	if err != nil {
+		return nil, err
+	}
+	spanId, err := hex.DecodeString(tc.TraceParent.ParentId())
+	if err != nil {
+		return nil, err
+	}
+
+	c := traceContext{
+		TraceId: traceId,
+		SpanId:  spanId,
+		Flags:   tc.TraceParent.Flags(),
+	}
+	if tc.TraceState != nil {
+		c.TraceState = tc.TraceState.String()
+	}
+
+	return cbor.Marshal(c)
+}
+
+// Unmarshal parses CBOR encoded data produced by Marshal and returns the
+// TraceContext. An error is returned if the decoded TraceContext is not valid,
+// e.g. because of an all zero trace id.
+func Unmarshal(data []byte) (*tracecontext.TraceContext, error) {
+	c := traceContext{}
+	err := cbor.Unmarshal(data, &c)
+	if err != nil {
+		return nil, err
+	}
+
+	tc, err := tracecontext.NewTraceContext(hex.EncodeToString(c.TraceId), hex.EncodeToString(c.SpanId))
+	if err != nil {
+		return nil, err
+	}
+	tc.TraceParent.SetFlags(c.Flags)
+
+	ts, err := tracecontext.ParseTraceState(c.TraceState)
+	if err != nil {
+		return nil, err
+	}
+	tc.TraceState = ts
+
+	err = tc.Validate()
+	if err != nil {
+		return nil, err
+	}
+	return tc, nil
+}
@@ -0,0 +1,62 @@
+package tracecbor
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/fxamacker/cbor/v2"
+	tracecontext "github.com/garciasdos/w3c-trace-context"
+)
+
+func TestRoundTrip(t *testing.T) {
+	headers := http.Header{}
+	headers.Add(tracecontext.TraceParentHeader, "00-0af7651916cd43dd8448eb211c80319c-00f067aa0ba902b7-01")
+	headers.Add(tracecontext.TraceStateHeader, "vendor1=val1,vendor2=val2")
+	tc, _ := tracecontext.ParseTraceContext(headers)
+
+	data, err := Marshal(tc)
+	if err != nil {
+		t.Error("Failed to marshal trace context:", err)
+	}
+
+	newTc, err := Unmarshal(data)
+	if err != nil {
+		t.Error("Failed to unmarshal trace context:", err)
+	}
+	if newTc.TraceParent.String() != tc.TraceParent.String() {
+		t.Errorf("traceparent not equal: '%s'", newTc.TraceParent.String())
+	}
+	if newTc.TraceState.String() != tc.TraceState.String() {
+		t.Errorf("tracestate not equal: '%s'", newTc.TraceState.String())
+	}
+}
+
+func TestMarshalWithoutTraceParent(t *testing.T) {
+	_, err := Marshal(&tracecontext.TraceContext{})
+
+	if err == nil {
+		t.Error("TraceContext without TraceParent was marshaled")
+	}
+}
+
+func TestUnmarshalInvalid(t *testing.T) {
+	_, err := Unmarshal([]byte{0xff})
+
+	if err == nil {
+		t.Error("Invalid data was unmarshaled")
+	}
+}
+
+func TestUnmarshalAllZeroIds(t *testing.T) {
+	inputs := []traceContext{
+		{TraceId: make([]byte, 16), SpanId: []byte{0, 0xf0, 0x67, 0xaa, 0x0b, 0xa9, 0x02, 0xb7}},
+		{TraceId: []byte{0x0a, 0xf7, 0x65, 0x19, 0x16, 0xcd, 0x43, 0xdd, 0x84, 0x48, 0xeb, 0x21, 0x1c, 0x80, 0x31, 0x9c}, SpanId: make([]byte, 8)},
+	}
+
+	for _, input := range inputs {
+		data, _ := cbor.Marshal(input)
+		if _, err := Unmarshal(data); err == nil {
+			t.Errorf("All zero ids %x, %x accepted", input.TraceId, input.SpanId)
+		}
+	}
+}
@@ -1,8 +1,14 @@
 package tracecontext
 
 import (
+	"encoding/base64"
+	"encoding/hex"
 	"errors"
+	"fmt"
+	"hash/fnv"
 	"net/http"
+	"strconv"
+	"strings"
 )
 
 const (
@@ -15,36 +21,131 @@ const (
 type TraceContext struct {
 	TraceParent *TraceParent
 	TraceState  *TraceState
+	// RawTraceState holds the discarded inbound tracestate header if
+	// PreserveStateOnParseError is enabled and the traceparent failed to parse
+	RawTraceState string
+
+	isRoot bool
 }
 
+// PreserveStateOnParseError makes HandleTraceContext keep the raw inbound
+// tracestate header in TraceContext.RawTraceState when the traceparent fails
+// to parse, e.g. for forensic logging. The tracestate is still discarded from
+// the returned headers as mandated by the specification.
+var PreserveStateOnParseError = false
+
 // ParseTraceContext attempts to extract TraceContext information from a given
 // set of headers. Partial data may be returned per the W3C specification.
 // If parsing completely fails, an error is returned.
 func ParseTraceContext(headers http.Header) (*TraceContext, error) {
+	traceContext, result := ParseTraceContextDetailed(headers)
+	if result.TraceParentErr != nil {
+		return nil, result.TraceParentErr
+	}
+	return traceContext, nil
+}
+
+// ParseResult holds the errors that occurred while parsing the traceparent
+// and tracestate headers
+type ParseResult struct {
+	TraceParentErr error
+	TraceStateErr  error
+}
+
+// ParseTraceContextDetailed parses the headers like ParseTraceContext, but
+// reports the traceparent and tracestate errors separately. If the
+// traceparent fails to parse, no TraceContext is returned and the tracestate
+// is not parsed. If only the tracestate fails to parse, the TraceContext is
+// returned without TraceState.
+func ParseTraceContextDetailed(headers http.Header) (*TraceContext, ParseResult) {
+	return parseTraceContextDetailed(headers.Get)
+}
+
+// ParseTraceContextFunc attempts to extract TraceContext information like
+// ParseTraceContext, reading the header values with the provided lookup
+// function. This supports frameworks that don't expose http.Header. The
+// function is called with the lowercase header names and must return an
+// empty string for absent headers.
+func ParseTraceContextFunc(get func(name string) string) (*TraceContext, error) {
+	traceContext, result := parseTraceContextDetailed(get)
+	if result.TraceParentErr != nil {
+		return nil, result.TraceParentErr
+	}
+	return traceContext, nil
+}
+
+// ParseTraceContextFrom attempts to extract TraceContext information like
+// ParseTraceContext from the headers of a request abstraction exposing them
+// via a Headers method
+func ParseTraceContextFrom(src interface{ Headers() http.Header }) (*TraceContext, error) {
+	return ParseTraceContext(src.Headers())
+}
+
+// parseTraceContextDetailed contains the logic of ParseTraceContextDetailed
+// for an arbitrary header lookup function
+func parseTraceContextDetailed(get func(name string) string) (*TraceContext, ParseResult) {
 	traceContext := TraceContext{}
+	result := ParseResult{}
 
-	traceparentHeader := headers.Get(TraceParentHeader)
+	traceparentHeader := get(TraceParentHeader)
 	traceParent, err := ParseTraceParent(traceparentHeader)
 	// If the vendor failed to parse traceparent, it MUST NOT attempt to parse tracestate
 	if err != nil {
-		return nil, err
+		result.TraceParentErr = err
+		return nil, result
 	}
 	traceContext.TraceParent = traceParent
 
-	tracestateHeader := headers.Get(TraceStateHeader)
+	tracestateHeader := get(TraceStateHeader)
 	traceState, err := ParseTraceState(tracestateHeader)
 	//failure to parse tracestate MUST NOT affect the parsing of traceparent
 	if err == nil {
 		traceContext.TraceState = traceState
+	} else {
+		result.TraceStateErr = err
+	}
+
+	return &traceContext, result
+}
+
+// ParseAndCanonicalize parses the provided traceparent and tracestate header
+// values like ParseTraceContext and additionally returns their canonical
+// serialization, e.g. for proxies forwarding cleaned-up headers. Optional
+// whitespace around the traceparent, whitespace within the tracestate and
+// empty tracestate members are removed and higher versions are downgraded. The
+// canonical tracestate is empty if it could not be parsed.
+func ParseAndCanonicalize(traceparent, tracestate string) (*TraceContext, string, string, error) {
+	traceParent, err := ParseTraceParent(strings.Trim(traceparent, " \t"))
+	if err != nil {
+		return nil, "", "", err
+	}
+	traceContext := TraceContext{
+		TraceParent: traceParent,
+	}
+
+	canonicalState := ""
+	traceState, err := ParseTraceState(tracestate)
+	if err == nil {
+		traceContext.TraceState = traceState
+		canonicalState = traceState.String()
 	}
 
-	return &traceContext, nil
+	return &traceContext, traceParent.String(), canonicalState, nil
+}
+
+// HasTraceContext returns true if the headers contain a non-empty
+// traceparent header. The header isn't validated.
+func HasTraceContext(headers http.Header) bool {
+	return headers.Get(TraceParentHeader) != ""
 }
 
 // HandleTraceContext implements handling of the trace context read from the
 // input headers. A copy of the headers is returned with only traceparent and
 // tracestate modified.
 // If no trace context information is present, it will be added.
+// If the traceparent cannot be parsed (e.g. because of the invalid version
+// ff), the trace is restarted with a new traceparent and the tracestate is
+// discarded.
 // The TraceState will be mutated:
 //   * parentId will be set to the provided value or generated randomly if left
 //     empty
@@ -67,10 +168,16 @@ func HandleTraceContext(headers *http.Header, parentId string, member *TraceStat
 			if err != nil {
 				return nil, nil, err
 			}
+			if PreserveStateOnParseError {
+				tc.RawTraceState = headers.Get(TraceStateHeader)
+			}
 			newTraceContext = tc
 		} else {
 			newTraceContext = tc
-			newTraceContext.Mutate(parentId, sampling, member)
+			err = newTraceContext.Mutate(parentId, sampling, member)
+			if err != nil {
+				return nil, nil, err
+			}
 		}
 	} else {
 		// If a tracestate header is received without an accompanying
@@ -87,24 +194,53 @@ func HandleTraceContext(headers *http.Header, parentId string, member *TraceStat
 	return &newHeaders, newTraceContext, nil
 }
 
+// HandleTraceContextChanges handles the trace context like HandleTraceContext
+// and additionally reports which of the traceparent and tracestate headers
+// were changed. The map holds the old and new value for each changed header.
+func HandleTraceContextChanges(headers *http.Header, parentId string, member *TraceStateMember, sampling SamplingBehavior) (*http.Header, *TraceContext, map[string][2]string, error) {
+	newHeaders, tc, err := HandleTraceContext(headers, parentId, member, sampling)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	changes := map[string][2]string{}
+	for _, name := range []string{TraceParentHeader, TraceStateHeader} {
+		oldValue := headers.Get(name)
+		newValue := newHeaders.Get(name)
+		if oldValue != newValue {
+			changes[name] = [2]string{oldValue, newValue}
+		}
+	}
+
+	return newHeaders, tc, changes, nil
+}
+
 func HandleKongTraceContext(headers map[string][]string, parentId string, member *TraceStateMember, sampling SamplingBehavior) (*http.Header, *TraceContext, error) {
 	httpHeaders := convertToHTTPHeader(headers)
 	var newTraceContext *TraceContext
+	var rawTraceState string
 
-	if traceParent, exists := headers[TraceParentHeader]; exists && len(traceParent) > 0 {
+	if hasHeaderValues(headers, TraceParentHeader) {
 		tc, err := ParseTraceContext(httpHeaders)
 		if err != nil {
 			// If parsing fails, the vendor creates a new traceparent header and
 			// deletes the tracestate
+			if PreserveStateOnParseError {
+				rawTraceState = httpHeaders.Get(TraceStateHeader)
+			}
 			httpHeaders.Del(TraceStateHeader)
 			tc, err := GenerateTraceContext(parentId, member, sampling)
 			if err != nil {
 				return nil, nil, err
 			}
+			tc.RawTraceState = rawTraceState
 			newTraceContext = tc
 		} else {
 			newTraceContext = tc
-			newTraceContext.Mutate(parentId, sampling, member)
+			err = newTraceContext.Mutate(parentId, sampling, member)
+			if err != nil {
+				return nil, nil, err
+			}
 		}
 	} else {
 		// If a tracestate header is received without an accompanying
@@ -123,6 +259,44 @@ func HandleKongTraceContext(headers map[string][]string, parentId string, member
 	return &httpHeaders, newTraceContext, nil
 }
 
+// InjectIntoStringSliceMap writes the traceparent and tracestate headers to a
+// raw headers map like WriteHeaders, without converting it to http.Header.
+// Existing entries of the same name are overwritten regardless of their case
+// and all other entries are left untouched.
+func InjectIntoStringSliceMap(m map[string][]string, tc *TraceContext) {
+	if tc.TraceParent != nil {
+		setHeaderValue(m, TraceParentHeader, tc.TraceParent.String())
+	}
+
+	// Vendors MUST accept empty tracestate headers but SHOULD avoid sending them
+	if tc.TraceState != nil && len(tc.TraceState.Members) > 0 {
+		setHeaderValue(m, TraceStateHeader, tc.TraceState.String())
+	}
+}
+
+// setHeaderValue replaces all entries matching the header name
+// case-insensitively with a single entry holding the value
+func setHeaderValue(m map[string][]string, name string, value string) {
+	for key := range m {
+		if strings.EqualFold(key, name) {
+			delete(m, key)
+		}
+	}
+	m[name] = []string{value}
+}
+
+// hasHeaderValues returns true if the raw headers map contains at least one
+// value for the provided header name. Header names are matched
+// case-insensitively.
+func hasHeaderValues(headers map[string][]string, name string) bool {
+	for key, values := range headers {
+		if strings.EqualFold(key, name) && len(values) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
 func convertToHTTPHeader(headers map[string][]string) http.Header {
 	httpHeaders := http.Header{}
 	for key, values := range headers {
@@ -142,12 +316,12 @@ func convertToHTTPHeader(headers map[string][]string) http.Header {
 // Errors will be returned if the random value generation fails or if the
 // provided key or value don't match the allowed format.
 func GenerateTraceContext(parentId string, member *TraceStateMember, sampling SamplingBehavior) (*TraceContext, error) {
-	traceId, err := randomHex(16)
+	traceId, err := idGenerator.NewTraceID()
 	if err != nil {
 		return nil, err
 	}
 	if parentId == "" {
-		parentId, err = randomHex(8)
+		parentId, err = idGenerator.NewSpanID()
 		if err != nil {
 			return nil, err
 		}
@@ -168,7 +342,7 @@ func GenerateTraceContext(parentId string, member *TraceStateMember, sampling Sa
 		ts = NewEmptyTraceState()
 	} else {
 		if member.Value == "" {
-			member.Value = parentId
+			member.Value = tp.parentId
 		}
 		ts, err = NewTraceState(*member)
 		if err != nil {
@@ -179,11 +353,61 @@ func GenerateTraceContext(parentId string, member *TraceStateMember, sampling Sa
 	tc := TraceContext{
 		TraceParent: tp,
 		TraceState:  ts,
+		isRoot:      true,
 	}
 
 	return &tc, nil
 }
 
+// GenerateTraceContextMulti generates a new TraceContext like
+// GenerateTraceContext, but seeds the tracestate with multiple members.
+// The members are applied in order as if TraceState.Mutate was called for each
+// of them, so the last member ends up left-most in the list. Members with an
+// empty value use the parent id as the vendor value.
+func GenerateTraceContextMulti(parentId string, members []TraceStateMember, sampling SamplingBehavior) (*TraceContext, error) {
+	tc, err := GenerateTraceContext(parentId, nil, sampling)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, member := range members {
+		if member.Value == "" {
+			member.Value = tc.TraceParent.parentId
+		}
+		err = tc.TraceState.Mutate(member)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return tc, nil
+}
+
+// NewChildOf generates a new TraceContext as a child of the provided parent.
+// The trace id and tracestate are copied from the parent, a new random parent
+// id is generated, the sampling behavior is applied and member is added to
+// the tracestate if it is not nil. The parent is not modified.
+func NewChildOf(parent *TraceContext, member *TraceStateMember, sampling SamplingBehavior) (*TraceContext, error) {
+	if parent.TraceParent == nil {
+		return nil, errors.New("TraceContext without TraceParent cannot be a parent")
+	}
+
+	tp := *parent.TraceParent
+	child := TraceContext{
+		TraceParent: &tp,
+	}
+	if parent.TraceState != nil {
+		child.TraceState = parent.TraceState.clone()
+	}
+
+	err := child.Mutate("", sampling, member)
+	if err != nil {
+		return nil, err
+	}
+
+	return &child, nil
+}
+
 // NewTraceContext returns a new TraceContext object initialized with the
 // provided traceId and parentId values.
 // An error is returned if the provided values don't match the format
@@ -202,9 +426,27 @@ func NewTraceContext(traceId string, parentId string) (*TraceContext, error) {
 	return &tc, nil
 }
 
+// FromOTLP returns a new TraceContext with an empty tracestate based on the
+// hex encoded trace id and span id of an OTLP span. An error is returned if
+// the ids don't match the format specification or are all zero.
+func FromOTLP(traceIDHex, spanIDHex string, sampled bool) (*TraceContext, error) {
+	tc, err := NewTraceContext(traceIDHex, spanIDHex)
+	if err != nil {
+		return nil, err
+	}
+	err = tc.TraceParent.validate()
+	if err != nil {
+		return nil, err
+	}
+	tc.TraceParent.SetSampled(sampled)
+
+	return tc, nil
+}
+
 // Mutate mutates the TraceContext object:
 //   * The parentId is updated with the provided value. If an empty value is
-//     provided, the parentId is randomly generated instead
+//     provided, the parentId is randomly generated instead, which is
+//     guaranteed to differ from the previous parentId
 //   * The sampled flag will be set in accordance with the selected sampling
 //     behavior
 //   * member is added to the tracestate list as long as member is not nil.
@@ -215,8 +457,7 @@ func (tc *TraceContext) Mutate(parentId string, sampling SamplingBehavior, membe
 		return errors.New("TraceContext without TraceParent cannot be mutated")
 	}
 	if parentId == "" {
-
-		parentId, err = randomHex(8)
+		parentId, err = newSpanIDDistinctFrom(tc.TraceParent.parentId)
 		if err != nil {
 			return err
 		}
@@ -233,7 +474,7 @@ func (tc *TraceContext) Mutate(parentId string, sampling SamplingBehavior, membe
 
 	if member != nil {
 		if member.Value == "" {
-			member.Value = parentId
+			member.Value = tc.TraceParent.parentId
 		}
 		if tc.TraceState == nil {
 			tc.TraceState, err = NewTraceState(*member)
@@ -241,21 +482,277 @@ func (tc *TraceContext) Mutate(parentId string, sampling SamplingBehavior, membe
 				return err
 			}
 		} else {
-			tc.TraceState.Mutate(*member)
+			err = tc.TraceState.Mutate(*member)
+			if err != nil {
+				return err
+			}
 		}
 	}
 	return nil
 }
 
+// MutationResult describes the changes made by MutateReport
+type MutationResult struct {
+	// SampledChanged is true if the sampled flag was flipped by the sampling
+	// behavior
+	SampledChanged bool
+}
+
+// MutateReport mutates the TraceContext like Mutate and reports the changes,
+// e.g. to count how often the sampling decision was overridden
+func (tc *TraceContext) MutateReport(parentId string, sampling SamplingBehavior, member *TraceStateMember) (MutationResult, error) {
+	if tc.TraceParent == nil {
+		return MutationResult{}, errors.New("TraceContext without TraceParent cannot be mutated")
+	}
+
+	sampled := tc.TraceParent.IsSampled()
+	err := tc.Mutate(parentId, sampling, member)
+	if err != nil {
+		return MutationResult{}, err
+	}
+
+	return MutationResult{
+		SampledChanged: tc.TraceParent.IsSampled() != sampled,
+	}, nil
+}
+
+// WriteOptions holds formatting quirks for peers that don't conform to the
+// specification. The zero value results in spec compliant output.
+type WriteOptions struct {
+	// UppercaseHex emits the hex fields of the traceparent in uppercase,
+	// which is not allowed by the specification
+	UppercaseHex bool
+}
+
 // WriteHeaders writes the traceparent and tracestate headers to the provided
 // headers object. Any existing headers of the same name are overwritten.
 func (tc *TraceContext) WriteHeaders(headers *http.Header) {
+	tc.WriteHeadersWith(headers, WriteOptions{})
+}
+
+// WriteHeadersWith writes the traceparent and tracestate headers like
+// WriteHeaders, applying the formatting quirks selected in opts.
+func (tc *TraceContext) WriteHeadersWith(headers *http.Header, opts WriteOptions) {
+	tc.writeHeaders(headers, opts)
+}
+
+// WriteHeadersReport writes the traceparent and tracestate headers like
+// WriteHeaders and reports which of the two headers were written, e.g. to
+// count how often an empty tracestate was omitted
+func (tc *TraceContext) WriteHeadersReport(headers *http.Header) (wroteParent, wroteState bool) {
+	return tc.writeHeaders(headers, WriteOptions{})
+}
+
+// writeHeaders contains the logic shared by the WriteHeaders methods
+func (tc *TraceContext) writeHeaders(headers *http.Header, opts WriteOptions) (wroteParent, wroteState bool) {
 	if tc.TraceParent != nil {
-		headers.Set(TraceParentHeader, tc.TraceParent.String())
+		traceParent := tc.TraceParent.String()
+		if opts.UppercaseHex {
+			traceParent = strings.ToUpper(traceParent)
+		}
+		headers.Set(TraceParentHeader, traceParent)
+		wroteParent = true
 	}
 
 	// Vendors MUST accept empty tracestate headers but SHOULD avoid sending them
 	if tc.TraceState != nil && len(tc.TraceState.Members) > 0 {
 		headers.Set(TraceStateHeader, tc.TraceState.String())
+		wroteState = true
+	}
+
+	return wroteParent, wroteState
+}
+
+// Hash returns a hash over the trace id, parent id and flags of the
+// TraceContext. The hash is stable across process runs and can be used as a
+// cache key.
+func (tc *TraceContext) Hash() uint64 {
+	h := fnv.New64a()
+	if tc.TraceParent != nil {
+		h.Write([]byte(tc.TraceParent.traceId))
+		h.Write([]byte(tc.TraceParent.parentId))
+		h.Write([]byte{tc.TraceParent.flags})
+	}
+	return h.Sum64()
+}
+
+// LogFields returns the trace context as structured log fields following the
+// OpenTelemetry log field conventions. An empty map is returned if there is
+// no TraceParent.
+func (tc *TraceContext) LogFields() map[string]any {
+	fields := map[string]any{}
+	if tc.TraceParent != nil {
+		fields["trace_id"] = tc.TraceParent.traceId
+		fields["span_id"] = tc.TraceParent.parentId
+		fields["trace_flags"] = fmt.Sprintf("%02x", tc.TraceParent.flags)
+	}
+	return fields
+}
+
+// CorrelationID returns the trace id and parent id joined by a dash as a single
+// token, e.g. for use as a log correlation key. An empty string is returned if
+// there is no TraceParent.
+func CorrelationID(tc *TraceContext) string {
+	if tc.TraceParent == nil {
+		return ""
+	}
+	return tc.TraceParent.traceId + "-" + tc.TraceParent.parentId
+}
+
+// IdempotencyKey returns a stable key derived from the trace id and parent id,
+// e.g. for deduplicating retried requests. The 24 id bytes are encoded with
+// unpadded base64url, resulting in 32 URL-safe characters. An empty string is
+// returned if there is no TraceParent.
+func (tc *TraceContext) IdempotencyKey() string {
+	if tc.TraceParent == nil {
+		return ""
+	}
+	ids, err := hex.DecodeString(tc.TraceParent.traceId + tc.TraceParent.parentId)
+	if err != nil {
+		return ""
+	}
+	return base64.RawURLEncoding.EncodeToString(ids)
+}
+
+// ParseCorrelationID splits a correlation id returned by CorrelationID into
+// the trace id and span id. An error is returned if either part doesn't match
+// the format specification.
+func ParseCorrelationID(s string) (traceId, spanId string, err error) {
+	traceId, spanId, found := strings.Cut(s, "-")
+	if !found {
+		return "", "", errors.New("correlation id doesn't contain a dash")
+	}
+
+	tp := TraceParent{traceId: traceId, parentId: spanId}
+	err = tp.validate()
+	if err != nil {
+		return "", "", err
+	}
+	return traceId, spanId, nil
+}
+
+// Validate checks that the TraceContext is internally consistent and returns
+// the first problem found. This is useful for hand-constructed contexts.
+func (tc *TraceContext) Validate() error {
+	if tc.TraceParent == nil {
+		return errors.New("TraceContext without TraceParent")
+	}
+	err := tc.TraceParent.validate()
+	if err != nil {
+		return err
+	}
+	if tc.TraceState != nil {
+		return tc.TraceState.validate()
+	}
+	return nil
+}
+
+// ApplyDebugHeader sets the sampled flag if the header with the provided name
+// holds a truthy value as accepted by strconv.ParseBool, e.g. "1" or "true".
+// The flag is left unchanged otherwise.
+func (tc *TraceContext) ApplyDebugHeader(headers http.Header, headerName string) {
+	if tc.TraceParent == nil {
+		return
+	}
+	if debug, err := strconv.ParseBool(headers.Get(headerName)); err == nil && debug {
+		tc.TraceParent.SetSampled(true)
+	}
+}
+
+// OwnState returns the tracestate value previously written under the
+// provided vendor key. The boolean is false if the key is not present.
+func (tc *TraceContext) OwnState(vendorKey string) (string, bool) {
+	if tc.TraceState == nil {
+		return "", false
+	}
+	// Valid members never have an empty value
+	value := tc.TraceState.MemberValue(vendorKey)
+	return value, value != ""
+}
+
+// RefreshOwnState sets the value of the tracestate member with the provided
+// vendor key to the current parent id. An existing member is updated in place,
+// otherwise it is added to the beginning of the list.
+func (tc *TraceContext) RefreshOwnState(vendorKey string) error {
+	if tc.TraceParent == nil {
+		return errors.New("TraceContext without TraceParent cannot refresh its state")
+	}
+	if tc.TraceState == nil {
+		tc.TraceState = NewEmptyTraceState()
+	}
+
+	parentId := tc.TraceParent.parentId
+	if _, ok := tc.OwnState(vendorKey); ok {
+		return tc.TraceState.Refresh(vendorKey, parentId)
+	}
+	return tc.TraceState.Mutate(TraceStateMember{Key: vendorKey, Value: parentId})
+}
+
+// EnsureState adds the member to the beginning of the tracestate if its key is
+// absent, e.g. to repair a tracestate from which the entry was stripped. If
+// the key is present, the tracestate is left unchanged.
+func (tc *TraceContext) EnsureState(member TraceStateMember) error {
+	if _, ok := tc.OwnState(member.Key); ok {
+		return nil
+	}
+	if tc.TraceState == nil {
+		tc.TraceState = NewEmptyTraceState()
+	}
+	return tc.TraceState.Mutate(member)
+}
+
+// IsRoot returns true if the TraceContext was freshly generated and therefore
+// has no upstream parent. Parsed contexts are never root.
+func (tc *TraceContext) IsRoot() bool {
+	return tc.isRoot
+}
+
+// TraceParentHeaderValue returns the traceparent header value or an empty
+// string if there is no TraceParent
+func (tc *TraceContext) TraceParentHeaderValue() string {
+	if tc.TraceParent == nil {
+		return ""
+	}
+	return tc.TraceParent.String()
+}
+
+// ToMap returns the traceparent and tracestate header values as a map for
+// frameworks that propagate headers as map[string]string. Like WriteHeaders,
+// an empty tracestate is omitted.
+func (tc *TraceContext) ToMap() map[string]string {
+	m := map[string]string{}
+	if tc.TraceParent != nil {
+		m[TraceParentHeader] = tc.TraceParent.String()
+	}
+	if tc.TraceState != nil && len(tc.TraceState.Members) > 0 {
+		m[TraceStateHeader] = tc.TraceState.String()
+	}
+	return m
+}
+
+// FromMap attempts to extract TraceContext information from a map of header
+// values. Header names are matched case-insensitively. It behaves like
+// ParseTraceContext otherwise.
+func FromMap(m map[string]string) (*TraceContext, error) {
+	headers := http.Header{}
+	for key, value := range m {
+		headers.Set(key, value)
+	}
+	return ParseTraceContext(headers)
+}
+
+// IsLoop returns true if the parent id of the inbound traceparent matches the
+// span id this service previously used, which indicates a request loop
+func (tc *TraceContext) IsLoop(ownSpanID string) bool {
+	return tc.TraceParent != nil && tc.TraceParent.parentId == ownSpanID
+}
+
+// Compact returns the traceparent and tracestate header values joined by a
+// pipe for compact logging. The tracestate is omitted if it is empty.
+func (tc *TraceContext) Compact() string {
+	s := tc.TraceParentHeaderValue()
+	if tc.TraceState != nil && len(tc.TraceState.Members) > 0 {
+		s += "|" + tc.TraceState.String()
 	}
+	return s
 }
@@ -1,7 +1,9 @@
 package tracecontext
 
 import (
+	"fmt"
 	"net/http"
+	"strings"
 	"testing"
 )
 
@@ -47,6 +49,62 @@ func TestParseTraceContextEmptyTracestate(t *testing.T) {
 	}
 }
 
+func TestParseTraceContextDetailed(t *testing.T) {
+	headers := http.Header{}
+	headers.Add(TraceParentHeader, "00-0af7651916cd43dd8448eb211c80319c-00f067aa0ba902b7-01")
+	headers.Add(TraceStateHeader, "Vendor1=val1")
+
+	tc, result := ParseTraceContextDetailed(headers)
+
+	if result.TraceParentErr != nil {
+		t.Error("Unexpected traceparent error:", result.TraceParentErr)
+	}
+	if result.TraceStateErr == nil {
+		t.Error("No tracestate error returned")
+	}
+	if tc == nil || tc.TraceParent == nil {
+		t.Error("No trace parent returned")
+	}
+	if tc != nil && tc.TraceState != nil {
+		t.Error("Invalid trace state returned")
+	}
+}
+
+func TestParseTraceContextDetailedTraceParentError(t *testing.T) {
+	headers := http.Header{}
+	headers.Add(TraceParentHeader, "01-illegal")
+	headers.Add(TraceStateHeader, "Vendor1=val1")
+
+	tc, result := ParseTraceContextDetailed(headers)
+
+	if result.TraceParentErr == nil {
+		t.Error("No traceparent error returned")
+	}
+	if result.TraceStateErr != nil {
+		t.Error("tracestate was parsed")
+	}
+	if tc != nil {
+		t.Error("Trace context returned")
+	}
+}
+
+func TestHasTraceContext(t *testing.T) {
+	headers := http.Header{}
+	if HasTraceContext(headers) {
+		t.Error("Trace context detected in missing header")
+	}
+
+	headers.Set(TraceParentHeader, "")
+	if HasTraceContext(headers) {
+		t.Error("Trace context detected in empty header")
+	}
+
+	headers.Set(TraceParentHeader, "00-0af7651916cd43dd8448eb211c80319c-00f067aa0ba902b7-01")
+	if !HasTraceContext(headers) {
+		t.Error("Trace context not detected")
+	}
+}
+
 func TestGenerateTraceContext(t *testing.T) {
 	vendorName := "vendor"
 	vendorValue := "value"
@@ -186,6 +244,112 @@ func TestHandleTraceContextParsingError(t *testing.T) {
 	}
 }
 
+func TestHandleTraceContextFFVersion(t *testing.T) {
+	headers := http.Header{}
+	headers.Add(TraceParentHeader, "ff-0af7651916cd43dd8448eb211c80319c-00f067aa0ba902b7-01")
+	headers.Add(TraceStateHeader, "vendor1=val1")
+
+	newHeaders, tc, err := HandleTraceContext(&headers, "", nil, SamplingBehaviorPassThrough)
+
+	if err != nil {
+		t.Error("Failed to handle trace context:", err)
+	}
+	if tc.TraceParent.TraceId() == "0af7651916cd43dd8448eb211c80319c" {
+		t.Error("Trace was not restarted")
+	}
+	tp, err := ParseTraceParent(newHeaders.Get(TraceParentHeader))
+	if err != nil {
+		t.Error("Invalid traceparent header:", err)
+	}
+	if tp != nil && tp.Version() != HighestSupportedTraceContextVersion {
+		t.Error("Wrong version of new traceparent")
+	}
+	if newHeaders.Get(TraceStateHeader) != "" {
+		t.Error("TraceState header returned")
+	}
+}
+
+func TestHandleTraceContextPreserveStateOnParseError(t *testing.T) {
+	PreserveStateOnParseError = true
+	defer func() { PreserveStateOnParseError = false }()
+
+	headers := http.Header{}
+	headers.Add(TraceParentHeader, "01-illegal")
+	headers.Add(TraceStateHeader, "vendor1=val1")
+
+	newHeaders, tc, err := HandleTraceContext(&headers, "", nil, SamplingBehaviorPassThrough)
+
+	if err != nil {
+		t.Error("Failed to handle trace context:", err)
+	}
+	if tc.RawTraceState != "vendor1=val1" {
+		t.Errorf("Raw tracestate not preserved: '%s'", tc.RawTraceState)
+	}
+	if tc.TraceParent == nil || newHeaders.Get(TraceParentHeader) != tc.TraceParent.String() {
+		t.Error("No new traceparent issued")
+	}
+	if newHeaders.Get(TraceStateHeader) != "" {
+		t.Error("TraceState header returned")
+	}
+}
+
+func TestHandleTraceContextParsingErrorStateNotPreserved(t *testing.T) {
+	headers := http.Header{}
+	headers.Add(TraceParentHeader, "01-illegal")
+	headers.Add(TraceStateHeader, "vendor1=val1")
+
+	_, tc, _ := HandleTraceContext(&headers, "", nil, SamplingBehaviorPassThrough)
+
+	if tc.RawTraceState != "" {
+		t.Error("Raw tracestate preserved without opt-in")
+	}
+}
+
+func TestHandleTraceContextChanges(t *testing.T) {
+	headers := http.Header{}
+	headers.Add(TraceParentHeader, "00-0af7651916cd43dd8448eb211c80319c-00f067aa0ba902b7-01")
+	headers.Add(TraceStateHeader, "vendor1=val1")
+
+	newHeaders, _, changes, err := HandleTraceContextChanges(&headers, "b7ad6b7169203331", nil, SamplingBehaviorPassThrough)
+
+	if err != nil {
+		t.Error("Failed to handle trace context:", err)
+	}
+	if len(changes) != 1 {
+		t.Errorf("Wrong number of changes: %v", changes)
+	}
+	expected := [2]string{
+		"00-0af7651916cd43dd8448eb211c80319c-00f067aa0ba902b7-01",
+		"00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01",
+	}
+	if changes[TraceParentHeader] != expected {
+		t.Errorf("Wrong traceparent change: %v", changes[TraceParentHeader])
+	}
+	if newHeaders.Get(TraceParentHeader) != expected[1] {
+		t.Error("Wrong traceparent header returned")
+	}
+}
+
+func TestHandleTraceContextFFVersionAdditionalField(t *testing.T) {
+	headers := http.Header{}
+	headers.Add(TraceParentHeader, "ff-0af7651916cd43dd8448eb211c80319c-00f067aa0ba902b7-01-00")
+	downgraded := false
+	OnVersionDowngrade = func(uint8) { downgraded = true }
+	defer func() { OnVersionDowngrade = nil }()
+
+	_, tc, err := HandleTraceContext(&headers, "", nil, SamplingBehaviorPassThrough)
+
+	if err != nil {
+		t.Error("Failed to handle trace context:", err)
+	}
+	if tc.TraceParent.TraceId() == "0af7651916cd43dd8448eb211c80319c" {
+		t.Error("Trace was not restarted")
+	}
+	if downgraded {
+		t.Error("Version ff reported as downgrade")
+	}
+}
+
 func TestHandleKongTraceContext(t *testing.T) {
 	headers := map[string][]string{
 		TraceParentHeader: {"00-0af7651916cd43dd8448eb211c80319c-00f067aa0ba902b7-01"},
@@ -274,6 +438,60 @@ func TestWriteHeadersNoEmptyTraceState(t *testing.T) {
 	}
 }
 
+func TestWriteHeadersWithDefaults(t *testing.T) {
+	headers := http.Header{}
+	tc, _ := NewTraceContext("0af7651916cd43dd8448eb211c80319c", "00f067aa0ba902b7")
+	tc.TraceState.Mutate(TraceStateMember{Key: "vendor1", Value: "Val1"})
+	tc.WriteHeadersWith(&headers, WriteOptions{})
+
+	if headers.Get(TraceParentHeader) != "00-0af7651916cd43dd8448eb211c80319c-00f067aa0ba902b7-00" {
+		t.Errorf("Wrong traceparent header written: '%s'", headers.Get(TraceParentHeader))
+	}
+	if headers.Get(TraceStateHeader) != "vendor1=Val1" {
+		t.Errorf("Wrong tracestate header written: '%s'", headers.Get(TraceStateHeader))
+	}
+}
+
+func TestWriteHeadersWithUppercaseHex(t *testing.T) {
+	headers := http.Header{}
+	tc, _ := NewTraceContext("0af7651916cd43dd8448eb211c80319c", "00f067aa0ba902b7")
+	tc.WriteHeadersWith(&headers, WriteOptions{UppercaseHex: true})
+
+	if headers.Get(TraceParentHeader) != "00-0AF7651916CD43DD8448EB211C80319C-00F067AA0BA902B7-00" {
+		t.Errorf("Wrong traceparent header written: '%s'", headers.Get(TraceParentHeader))
+	}
+}
+
+func TestWriteHeadersReportEmptyTraceState(t *testing.T) {
+	headers := http.Header{}
+	tc, _ := NewTraceContext("0af7651916cd43dd8448eb211c80319c", "00f067aa0ba902b7")
+	wroteParent, wroteState := tc.WriteHeadersReport(&headers)
+
+	if !wroteParent {
+		t.Error("traceparent header not reported")
+	}
+	if wroteState {
+		t.Error("Empty tracestate header reported as written")
+	}
+}
+
+func TestWriteHeadersReportTraceState(t *testing.T) {
+	headers := http.Header{}
+	tc, _ := NewTraceContext("0af7651916cd43dd8448eb211c80319c", "00f067aa0ba902b7")
+	tc.TraceState.Mutate(TraceStateMember{Key: "vendor1", Value: "Val1"})
+	wroteParent, wroteState := tc.WriteHeadersReport(&headers)
+
+	if !wroteParent {
+		t.Error("traceparent header not reported")
+	}
+	if !wroteState {
+		t.Error("tracestate header not reported")
+	}
+	if headers.Get(TraceStateHeader) != "vendor1=Val1" {
+		t.Errorf("Wrong tracestate header written: '%s'", headers.Get(TraceStateHeader))
+	}
+}
+
 func TestNewTraceContext(t *testing.T) {
 	traceId := "0af7651916cd43dd8448eb211c80319c"
 	parentId := "00f067aa0ba902b7"
@@ -291,3 +509,675 @@ func TestNewTraceContext(t *testing.T) {
 		t.Error("traceId or parentId not matching")
 	}
 }
+
+func TestHash(t *testing.T) {
+	tc1, _ := NewTraceContext("0af7651916cd43dd8448eb211c80319c", "00f067aa0ba902b7")
+	tc2, _ := NewTraceContext("0af7651916cd43dd8448eb211c80319c", "00f067aa0ba902b7")
+	tc3, _ := NewTraceContext("0af7651916cd43dd8448eb211c80319c", "00f067aa0ba902b8")
+	tc4, _ := NewTraceContext("0af7651916cd43dd8448eb211c80319c", "00f067aa0ba902b7")
+	tc4.TraceParent.SetSampled(true)
+
+	if tc1.Hash() != tc2.Hash() {
+		t.Error("Equal trace contexts have different hashes")
+	}
+	if tc1.Hash() == tc3.Hash() {
+		t.Error("Trace contexts with different parent ids have the same hash")
+	}
+	if tc1.Hash() == tc4.Hash() {
+		t.Error("Trace contexts with different flags have the same hash")
+	}
+}
+
+func TestLogFields(t *testing.T) {
+	tc, _ := NewTraceContext("0af7651916cd43dd8448eb211c80319c", "00f067aa0ba902b7")
+	tc.TraceParent.SetSampled(true)
+
+	fields := tc.LogFields()
+
+	if len(fields) != 3 {
+		t.Errorf("Incorrect number of fields %d", len(fields))
+	}
+	if fields["trace_id"] != "0af7651916cd43dd8448eb211c80319c" {
+		t.Error("trace_id not set right")
+	}
+	if fields["span_id"] != "00f067aa0ba902b7" {
+		t.Error("span_id not set right")
+	}
+	if fields["trace_flags"] != "01" {
+		t.Error("trace_flags not set right")
+	}
+}
+
+func TestLogFieldsEmpty(t *testing.T) {
+	tc := TraceContext{}
+
+	if len(tc.LogFields()) != 0 {
+		t.Error("Fields returned without TraceParent")
+	}
+}
+
+func TestGenerateTraceContextMulti(t *testing.T) {
+	members := []TraceStateMember{
+		{Key: "vendor1", Value: "val1"},
+		{Key: "vendor2", Value: "val2"},
+		{Key: "vendor3"},
+	}
+	tc, err := GenerateTraceContextMulti("00f067aa0ba902b7", members, SamplingBehaviorAlwaysSampled)
+
+	if err != nil {
+		t.Error("Failed to generate trace context:", err)
+	}
+	if len(tc.TraceState.Members) != 3 {
+		t.Errorf("Incorrect length %d of tracestate", len(tc.TraceState.Members))
+	}
+	if s := tc.TraceState.String(); s != "vendor3=00f067aa0ba902b7,vendor2=val2,vendor1=val1" {
+		t.Errorf("TraceState is not as expected: '%s'", s)
+	}
+	if members[2].Value != "" {
+		t.Error("Provided members were modified")
+	}
+}
+
+func TestGenerateTraceContextMultiDuplicate(t *testing.T) {
+	members := []TraceStateMember{
+		{Key: "vendor1", Value: "val1"},
+		{Key: "vendor2", Value: "val2"},
+		{Key: "vendor1", Value: "val3"},
+	}
+	tc, err := GenerateTraceContextMulti("", members, SamplingBehaviorPassThrough)
+
+	if err != nil {
+		t.Error("Failed to generate trace context:", err)
+	}
+	if s := tc.TraceState.String(); s != "vendor1=val3,vendor2=val2" {
+		t.Errorf("TraceState is not as expected: '%s'", s)
+	}
+}
+
+func TestGenerateTraceContextMultiIllegalKey(t *testing.T) {
+	members := []TraceStateMember{{Key: "Vendor1", Value: "val1"}}
+	_, err := GenerateTraceContextMulti("", members, SamplingBehaviorPassThrough)
+
+	if err == nil {
+		t.Error("Illegal key didn't cause an error")
+	}
+}
+
+func TestCorrelationID(t *testing.T) {
+	tc, _ := NewTraceContext("0af7651916cd43dd8448eb211c80319c", "00f067aa0ba902b7")
+
+	id := CorrelationID(tc)
+	if id != "0af7651916cd43dd8448eb211c80319c-00f067aa0ba902b7" {
+		t.Errorf("Wrong correlation id returned: '%s'", id)
+	}
+	if CorrelationID(tc) != id {
+		t.Error("Correlation id is not stable")
+	}
+	if CorrelationID(&TraceContext{}) != "" {
+		t.Error("Correlation id returned without TraceParent")
+	}
+}
+
+func TestIdempotencyKey(t *testing.T) {
+	tc, _ := NewTraceContext("0af7651916cd43dd8448eb211c80319c", "00f067aa0ba902b7")
+
+	key := tc.IdempotencyKey()
+	if key != "CvdlGRbNQ92ESOshHIAxnADwZ6oLqQK3" {
+		t.Errorf("Wrong idempotency key returned: '%s'", key)
+	}
+	if tc.IdempotencyKey() != key {
+		t.Error("Idempotency key is not stable")
+	}
+	if (&TraceContext{}).IdempotencyKey() != "" {
+		t.Error("Idempotency key returned without TraceParent")
+	}
+}
+
+func TestParseCorrelationID(t *testing.T) {
+	traceId, spanId, err := ParseCorrelationID("0af7651916cd43dd8448eb211c80319c-00f067aa0ba902b7")
+
+	if err != nil {
+		t.Error("Failed to parse correlation id:", err)
+	}
+	if traceId != "0af7651916cd43dd8448eb211c80319c" || spanId != "00f067aa0ba902b7" {
+		t.Errorf("Wrong ids returned: '%s', '%s'", traceId, spanId)
+	}
+}
+
+func TestParseCorrelationIDMalformed(t *testing.T) {
+	inputs := []string{
+		"",
+		"0af7651916cd43dd8448eb211c80319c",
+		"0af7651916cd43dd8448eb211c80319c-00f067aa0ba902b7-01",
+		"0AF7651916CD43DD8448EB211C80319C-00f067aa0ba902b7",
+		"00000000000000000000000000000000-00f067aa0ba902b7",
+		"0af7651916cd43dd8448eb211c80319c-0000000000000000",
+		"0af7651916cd43dd-00f067aa0ba902b7",
+	}
+
+	for _, input := range inputs {
+		if _, _, err := ParseCorrelationID(input); err == nil {
+			t.Errorf("Malformed correlation id '%s' parsed", input)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tc, _ := NewTraceContext("0af7651916cd43dd8448eb211c80319c", "00f067aa0ba902b7")
+	tc.TraceState.Mutate(TraceStateMember{Key: "vendor1", Value: "val1"})
+
+	if err := tc.Validate(); err != nil {
+		t.Error("Valid trace context not accepted:", err)
+	}
+	if err := (&TraceContext{TraceParent: tc.TraceParent}).Validate(); err != nil {
+		t.Error("Trace context without tracestate not accepted:", err)
+	}
+}
+
+func TestValidateInvalid(t *testing.T) {
+	member := TraceStateMember{Key: "vendor1", Value: "val1"}
+	tooManyMembers := TraceState{}
+	for i := 0; i < 33; i++ {
+		tooManyMembers.Members = append(tooManyMembers.Members, &TraceStateMember{Key: fmt.Sprintf("m%d", i), Value: "v"})
+	}
+
+	contexts := map[string]TraceContext{
+		"nil traceparent":  {},
+		"empty trace id":   {TraceParent: &TraceParent{parentId: "00f067aa0ba902b7"}},
+		"zero trace id":    {TraceParent: &TraceParent{traceId: "00000000000000000000000000000000", parentId: "00f067aa0ba902b7"}},
+		"zero parent id":   {TraceParent: &TraceParent{traceId: "0af7651916cd43dd8448eb211c80319c", parentId: "0000000000000000"}},
+		"ff version":       {TraceParent: &TraceParent{version: 255, traceId: "0af7651916cd43dd8448eb211c80319c", parentId: "00f067aa0ba902b7"}},
+		"invalid key":      {TraceParent: &TraceParent{traceId: "0af7651916cd43dd8448eb211c80319c", parentId: "00f067aa0ba902b7"}, TraceState: &TraceState{Members: []*TraceStateMember{{Key: "Vendor", Value: "val"}}}},
+		"invalid value":    {TraceParent: &TraceParent{traceId: "0af7651916cd43dd8448eb211c80319c", parentId: "00f067aa0ba902b7"}, TraceState: &TraceState{Members: []*TraceStateMember{{Key: "vendor", Value: "a,b"}}}},
+		"duplicate key":    {TraceParent: &TraceParent{traceId: "0af7651916cd43dd8448eb211c80319c", parentId: "00f067aa0ba902b7"}, TraceState: &TraceState{Members: []*TraceStateMember{&member, &member}}},
+		"too many members": {TraceParent: &TraceParent{traceId: "0af7651916cd43dd8448eb211c80319c", parentId: "00f067aa0ba902b7"}, TraceState: &tooManyMembers},
+	}
+
+	for name, tc := range contexts {
+		if err := tc.Validate(); err == nil {
+			t.Errorf("Invalid trace context with %s accepted", name)
+		}
+	}
+}
+
+func TestApplyDebugHeader(t *testing.T) {
+	cases := map[string]bool{
+		"1":     true,
+		"true":  true,
+		"0":     false,
+		"false": false,
+		"":      false,
+	}
+
+	for value, expected := range cases {
+		tc, _ := NewTraceContext("0af7651916cd43dd8448eb211c80319c", "00f067aa0ba902b7")
+		headers := http.Header{}
+		if value != "" {
+			headers.Set("X-Debug-Trace", value)
+		}
+
+		tc.ApplyDebugHeader(headers, "X-Debug-Trace")
+
+		if tc.TraceParent.IsSampled() != expected {
+			t.Errorf("Sampling flag not set right for '%s'", value)
+		}
+	}
+}
+
+func TestOwnState(t *testing.T) {
+	headers := http.Header{}
+	headers.Add(TraceParentHeader, "00-0af7651916cd43dd8448eb211c80319c-00f067aa0ba902b7-01")
+	headers.Add(TraceStateHeader, "vendor1=val1,vendor2=val2")
+	tc, _ := ParseTraceContext(headers)
+
+	if v, ok := tc.OwnState("vendor2"); !ok || v != "val2" {
+		t.Errorf("Wrong own state returned: '%s'", v)
+	}
+	if _, ok := tc.OwnState("vendor3"); ok {
+		t.Error("Absent own state found")
+	}
+	if _, ok := (&TraceContext{}).OwnState("vendor1"); ok {
+		t.Error("Own state found without tracestate")
+	}
+}
+
+func TestRefreshOwnState(t *testing.T) {
+	headers := http.Header{}
+	headers.Add(TraceParentHeader, "00-0af7651916cd43dd8448eb211c80319c-00f067aa0ba902b7-01")
+	headers.Add(TraceStateHeader, "vendor1=val1,vendor2=00f067aa0ba902b7")
+	tc, _ := ParseTraceContext(headers)
+	tc.Mutate("b7ad6b7169203331", SamplingBehaviorPassThrough, nil)
+
+	err := tc.RefreshOwnState("vendor2")
+
+	if err != nil {
+		t.Error("Failed to refresh own state:", err)
+	}
+	if s := tc.TraceState.String(); s != "vendor1=val1,vendor2=b7ad6b7169203331" {
+		t.Errorf("Own state not refreshed in place: '%s'", s)
+	}
+
+	tc.RefreshOwnState("vendor3")
+	if v, _ := tc.OwnState("vendor3"); v != "b7ad6b7169203331" {
+		t.Errorf("Absent own state not added: '%s'", v)
+	}
+}
+
+func TestEnsureStateAbsent(t *testing.T) {
+	headers := http.Header{}
+	headers.Add(TraceParentHeader, "00-0af7651916cd43dd8448eb211c80319c-00f067aa0ba902b7-01")
+	headers.Add(TraceStateHeader, "vendor1=val1")
+	tc, _ := ParseTraceContext(headers)
+
+	err := tc.EnsureState(TraceStateMember{Key: "vendor2", Value: "val2"})
+
+	if err != nil {
+		t.Error("Failed to ensure state:", err)
+	}
+	if s := tc.TraceState.String(); s != "vendor2=val2,vendor1=val1" {
+		t.Errorf("Absent member not added at the front: '%s'", s)
+	}
+}
+
+func TestEnsureStatePresent(t *testing.T) {
+	headers := http.Header{}
+	headers.Add(TraceParentHeader, "00-0af7651916cd43dd8448eb211c80319c-00f067aa0ba902b7-01")
+	headers.Add(TraceStateHeader, "vendor1=val1,vendor2=val2")
+	tc, _ := ParseTraceContext(headers)
+
+	err := tc.EnsureState(TraceStateMember{Key: "vendor2", Value: "other"})
+
+	if err != nil {
+		t.Error("Failed to ensure state:", err)
+	}
+	if s := tc.TraceState.String(); s != "vendor1=val1,vendor2=val2" {
+		t.Errorf("Present member changed: '%s'", s)
+	}
+}
+
+func TestNewChildOf(t *testing.T) {
+	parent, _ := NewTraceContext("0af7651916cd43dd8448eb211c80319c", "00f067aa0ba902b7")
+	parent.TraceState.Mutate(TraceStateMember{Key: "vendor1", Value: "val1"})
+
+	child, err := NewChildOf(parent, &TraceStateMember{Key: "vendor2", Value: "val2"}, SamplingBehaviorAlwaysSampled)
+
+	if err != nil {
+		t.Error("Failed to create child:", err)
+	}
+	if child.TraceParent.TraceId() != parent.TraceParent.TraceId() {
+		t.Error("trace id doesn't match the parent")
+	}
+	if child.TraceParent.ParentId() == parent.TraceParent.ParentId() {
+		t.Error("parent id was not renewed")
+	}
+	if !child.TraceParent.IsSampled() {
+		t.Error("Sampling flag not set right")
+	}
+	if child.TraceState.String() != "vendor2=val2,vendor1=val1" {
+		t.Errorf("TraceState is not as expected: '%s'", child.TraceState.String())
+	}
+	if parent.TraceParent.ParentId() != "00f067aa0ba902b7" || parent.TraceParent.IsSampled() || len(parent.TraceState.Members) != 1 {
+		t.Error("parent was modified")
+	}
+}
+
+func TestNewChildOfWithoutTraceParent(t *testing.T) {
+	_, err := NewChildOf(&TraceContext{}, nil, SamplingBehaviorPassThrough)
+
+	if err == nil {
+		t.Error("Child created without TraceParent")
+	}
+}
+
+func TestIsRoot(t *testing.T) {
+	generated, _ := GenerateTraceContext("", nil, SamplingBehaviorPassThrough)
+	if !generated.IsRoot() {
+		t.Error("Generated trace context is not root")
+	}
+
+	headers := http.Header{}
+	headers.Add(TraceParentHeader, "00-0af7651916cd43dd8448eb211c80319c-00f067aa0ba902b7-01")
+	parsed, _ := ParseTraceContext(headers)
+	if parsed.IsRoot() {
+		t.Error("Parsed trace context is root")
+	}
+
+	child, _ := NewChildOf(generated, nil, SamplingBehaviorPassThrough)
+	if child.IsRoot() {
+		t.Error("Child trace context is root")
+	}
+}
+
+func TestHandleKongTraceContextCanonicalHeaderNames(t *testing.T) {
+	headers := map[string][]string{
+		"Traceparent": {"00-0af7651916cd43dd8448eb211c80319c-00f067aa0ba902b7-01"},
+		"Tracestate":  {"vendor1=val1"},
+	}
+
+	newHeaders, tc, err := HandleKongTraceContext(headers, "", nil, SamplingBehaviorPassThrough)
+
+	if err != nil {
+		t.Error("Failed to handle trace context:", err)
+	}
+	if tc.TraceParent.TraceId() != "0af7651916cd43dd8448eb211c80319c" {
+		t.Error("traceparent was not recognized")
+	}
+	if newHeaders.Get(TraceStateHeader) != "vendor1=val1" {
+		t.Errorf("TraceState is not as expected: '%s'", newHeaders.Get(TraceStateHeader))
+	}
+}
+
+func TestTraceParentHeaderValue(t *testing.T) {
+	tc, _ := NewTraceContext("0af7651916cd43dd8448eb211c80319c", "00f067aa0ba902b7")
+
+	if v := tc.TraceParentHeaderValue(); v != "00-0af7651916cd43dd8448eb211c80319c-00f067aa0ba902b7-00" {
+		t.Errorf("Wrong traceparent value: '%s'", v)
+	}
+	if v := (&TraceContext{}).TraceParentHeaderValue(); v != "" {
+		t.Errorf("Value returned without TraceParent: '%s'", v)
+	}
+}
+
+func TestToMap(t *testing.T) {
+	tc, _ := NewTraceContext("0af7651916cd43dd8448eb211c80319c", "00f067aa0ba902b7")
+
+	m := tc.ToMap()
+	if len(m) != 1 || m[TraceParentHeader] != "00-0af7651916cd43dd8448eb211c80319c-00f067aa0ba902b7-00" {
+		t.Errorf("Wrong map returned: %v", m)
+	}
+
+	tc.TraceState.Mutate(TraceStateMember{Key: "vendor1", Value: "val1"})
+	m = tc.ToMap()
+	if len(m) != 2 || m[TraceStateHeader] != "vendor1=val1" {
+		t.Errorf("Wrong map returned: %v", m)
+	}
+}
+
+func TestFromMapRoundTrip(t *testing.T) {
+	tc, _ := NewTraceContext("0af7651916cd43dd8448eb211c80319c", "00f067aa0ba902b7")
+	tc.TraceParent.SetSampled(true)
+	tc.TraceState.Mutate(TraceStateMember{Key: "vendor1", Value: "val1"})
+
+	newTc, err := FromMap(tc.ToMap())
+
+	if err != nil {
+		t.Error("Failed to parse trace context from map:", err)
+	}
+	if newTc.TraceParent.String() != tc.TraceParent.String() {
+		t.Errorf("traceparent not equal: '%s'", newTc.TraceParent.String())
+	}
+	if newTc.TraceState.String() != tc.TraceState.String() {
+		t.Errorf("tracestate not equal: '%s'", newTc.TraceState.String())
+	}
+
+	if _, err := FromMap(map[string]string{}); err == nil {
+		t.Error("Parsed trace context from empty map")
+	}
+}
+
+func TestInjectIntoStringSliceMap(t *testing.T) {
+	m := map[string][]string{
+		"Content-Type": {"application/json"},
+		"Traceparent":  {"00-0af7651916cd43dd8448eb211c80319c-00f067aa0ba902b7-01"},
+		"tracestate":   {"vendor1=val1"},
+	}
+	tc, _ := NewTraceContext("0af7651916cd43dd8448eb211c80319c", "b7ad6b7169203331")
+	tc.TraceState.Mutate(TraceStateMember{Key: "vendor2", Value: "val2"})
+
+	InjectIntoStringSliceMap(m, tc)
+
+	if len(m) != 3 {
+		t.Errorf("Wrong number of entries: %v", m)
+	}
+	if len(m["Content-Type"]) != 1 || m["Content-Type"][0] != "application/json" {
+		t.Error("Other entry was modified")
+	}
+	if len(m[TraceParentHeader]) != 1 || m[TraceParentHeader][0] != "00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-00" {
+		t.Errorf("Wrong traceparent set: %v", m[TraceParentHeader])
+	}
+	if len(m[TraceStateHeader]) != 1 || m[TraceStateHeader][0] != "vendor2=val2" {
+		t.Errorf("Wrong tracestate set: %v", m[TraceStateHeader])
+	}
+}
+
+func TestFromOTLP(t *testing.T) {
+	tc, err := FromOTLP("0af7651916cd43dd8448eb211c80319c", "00f067aa0ba902b7", true)
+
+	if err != nil {
+		t.Error("Failed to create trace context:", err)
+	}
+	if tc.TraceParent.String() != "00-0af7651916cd43dd8448eb211c80319c-00f067aa0ba902b7-01" {
+		t.Errorf("Wrong traceparent: '%s'", tc.TraceParent.String())
+	}
+	if len(tc.TraceState.Members) != 0 {
+		t.Error("Generated tracestate is not empty")
+	}
+}
+
+func TestFromOTLPInvalid(t *testing.T) {
+	inputs := [][2]string{
+		{"0af7651916cd43dd8448eb211c80319", "00f067aa0ba902b7"},
+		{"0af7651916cd43dd8448eb211c80319c", "00f067aa0ba902bz"},
+		{"0AF7651916CD43DD8448EB211C80319C", "00f067aa0ba902b7"},
+		{"00000000000000000000000000000000", "00f067aa0ba902b7"},
+		{"0af7651916cd43dd8448eb211c80319c", "0000000000000000"},
+	}
+
+	for _, input := range inputs {
+		if _, err := FromOTLP(input[0], input[1], false); err == nil {
+			t.Errorf("Invalid ids %v accepted", input)
+		}
+	}
+}
+
+func TestIsLoop(t *testing.T) {
+	headers := http.Header{}
+	headers.Add(TraceParentHeader, "00-0af7651916cd43dd8448eb211c80319c-00f067aa0ba902b7-01")
+	tc, _ := ParseTraceContext(headers)
+
+	if !tc.IsLoop("00f067aa0ba902b7") {
+		t.Error("Loop not detected")
+	}
+	if tc.IsLoop("b7ad6b7169203331") {
+		t.Error("Loop detected for different span id")
+	}
+	if (&TraceContext{}).IsLoop("") {
+		t.Error("Loop detected without TraceParent")
+	}
+}
+
+func TestCompact(t *testing.T) {
+	tc, _ := NewTraceContext("0af7651916cd43dd8448eb211c80319c", "00f067aa0ba902b7")
+
+	if s := tc.Compact(); s != "00-0af7651916cd43dd8448eb211c80319c-00f067aa0ba902b7-00" {
+		t.Errorf("Wrong compact value: '%s'", s)
+	}
+
+	tc.TraceState.Mutate(TraceStateMember{Key: "vendor1", Value: "val1"})
+	if s := tc.Compact(); s != "00-0af7651916cd43dd8448eb211c80319c-00f067aa0ba902b7-00|vendor1=val1" {
+		t.Errorf("Wrong compact value: '%s'", s)
+	}
+}
+
+func TestParseAndCanonicalize(t *testing.T) {
+	tc, traceparent, tracestate, err := ParseAndCanonicalize(
+		" 00-0af7651916cd43dd8448eb211c80319c-00f067aa0ba902b7-01\t",
+		" vendor1=val1 ,, \tvendor2=val2 ",
+	)
+
+	if err != nil {
+		t.Error("Failed to parse:", err)
+	}
+	if traceparent != "00-0af7651916cd43dd8448eb211c80319c-00f067aa0ba902b7-01" {
+		t.Errorf("Wrong canonical traceparent: '%s'", traceparent)
+	}
+	if tracestate != "vendor1=val1,vendor2=val2" {
+		t.Errorf("Wrong canonical tracestate: '%s'", tracestate)
+	}
+	if tc.TraceState.Len() != 2 {
+		t.Error("tracestate not parsed")
+	}
+}
+
+func TestParseAndCanonicalizeInvalid(t *testing.T) {
+	_, _, tracestate, err := ParseAndCanonicalize("00-0af7651916cd43dd8448eb211c80319c-00f067aa0ba902b7-01", "Vendor=1")
+	if err != nil || tracestate != "" {
+		t.Errorf("Invalid tracestate not dropped: '%s', %v", tracestate, err)
+	}
+
+	if _, _, _, err := ParseAndCanonicalize("01-illegal", ""); err == nil {
+		t.Error("Invalid traceparent parsed")
+	}
+}
+
+func TestParseTraceContextFunc(t *testing.T) {
+	values := map[string]string{
+		"traceparent": "00-0af7651916cd43dd8448eb211c80319c-00f067aa0ba902b7-01",
+		"tracestate":  "vendor1=val1",
+	}
+
+	tc, err := ParseTraceContextFunc(func(name string) string {
+		return values[name]
+	})
+
+	if err != nil {
+		t.Error("Failed to parse trace context:", err)
+	}
+	if tc.TraceParent.String() != "00-0af7651916cd43dd8448eb211c80319c-00f067aa0ba902b7-01" {
+		t.Error("traceparent not parsed correctly")
+	}
+	if tc.TraceState.String() != "vendor1=val1" {
+		t.Error("tracestate not parsed correctly")
+	}
+
+	_, err = ParseTraceContextFunc(func(name string) string { return "" })
+	if err == nil {
+		t.Error("Missing traceparent parsed")
+	}
+}
+
+func TestMutateReportSampledChanged(t *testing.T) {
+	tc, _ := NewTraceContext("0af7651916cd43dd8448eb211c80319c", "00f067aa0ba902b7")
+
+	result, err := tc.MutateReport("", SamplingBehaviorAlwaysSampled, nil)
+
+	if err != nil {
+		t.Error("Failed to mutate:", err)
+	}
+	if !result.SampledChanged {
+		t.Error("Flipped sampled flag not reported")
+	}
+}
+
+func TestMutateReportSampledUnchanged(t *testing.T) {
+	tc, _ := NewTraceContext("0af7651916cd43dd8448eb211c80319c", "00f067aa0ba902b7")
+	tc.TraceParent.SetSampled(true)
+
+	result, err := tc.MutateReport("", SamplingBehaviorAlwaysSampled, nil)
+
+	if err != nil {
+		t.Error("Failed to mutate:", err)
+	}
+	if result.SampledChanged {
+		t.Error("Unchanged sampled flag reported as changed")
+	}
+
+	result, _ = tc.MutateReport("", SamplingBehaviorPassThrough, nil)
+	if result.SampledChanged {
+		t.Error("Pass through reported as changed")
+	}
+}
+
+func TestEmptyParentIDLenient(t *testing.T) {
+	tc, err := GenerateTraceContext("", nil, SamplingBehaviorPassThrough)
+	if err != nil {
+		t.Error("Failed to generate trace context:", err)
+	}
+
+	err = tc.Mutate("", SamplingBehaviorPassThrough, nil)
+	if err != nil {
+		t.Error("Failed to mutate:", err)
+	}
+	if tc.TraceParent.ParentId() == "" {
+		t.Error("parent id not generated")
+	}
+}
+
+type headersSource struct {
+	headers http.Header
+}
+
+func (s headersSource) Headers() http.Header {
+	return s.headers
+}
+
+func TestParseTraceContextFrom(t *testing.T) {
+	headers := http.Header{}
+	headers.Add(TraceParentHeader, "00-0af7651916cd43dd8448eb211c80319c-00f067aa0ba902b7-01")
+	headers.Add(TraceStateHeader, "vendor1=val1")
+
+	tc, err := ParseTraceContextFrom(headersSource{headers: headers})
+
+	if err != nil {
+		t.Error("Failed to parse trace context:", err)
+	}
+	if tc.TraceParent.String() != "00-0af7651916cd43dd8448eb211c80319c-00f067aa0ba902b7-01" {
+		t.Error("traceparent not parsed correctly")
+	}
+	if tc.TraceState.String() != "vendor1=val1" {
+		t.Error("tracestate not parsed correctly")
+	}
+}
+
+func TestHandleTraceContextMutateError(t *testing.T) {
+	SetIDGenerator(fixedIDGenerator{})
+	defer SetIDGenerator(nil)
+	headers := http.Header{}
+	headers.Set(TraceParentHeader, "00-0af7651916cd43dd8448eb211c80319c-00f067aa0ba902b7-01")
+
+	if _, _, err := HandleTraceContext(&headers, "", nil, SamplingBehaviorPassThrough); err == nil {
+		t.Error("Mutate error not returned")
+	}
+	if _, _, err := HandleKongTraceContext(headers, "", nil, SamplingBehaviorPassThrough); err == nil {
+		t.Error("Mutate error not returned for Kong")
+	}
+}
+
+func TestMutateMemberTooLongError(t *testing.T) {
+	tc, _ := NewTraceContext("0af7651916cd43dd8448eb211c80319c", "00f067aa0ba902b7")
+	member := TraceStateMember{Key: "vendor1", Value: strings.Repeat("a", MaxMemberLength)}
+
+	err := tc.Mutate("b7ad6b7169203331", SamplingBehaviorPassThrough, &member)
+
+	if err != ErrMemberTooLong {
+		t.Errorf("Wrong error for oversized member: %v", err)
+	}
+
+	headers := http.Header{}
+	headers.Set(TraceParentHeader, "00-0af7651916cd43dd8448eb211c80319c-00f067aa0ba902b7-01")
+	headers.Set(TraceStateHeader, "vendor2=val2")
+	if _, _, err := HandleTraceContext(&headers, "b7ad6b7169203331", &member, SamplingBehaviorPassThrough); err != ErrMemberTooLong {
+		t.Errorf("Wrong error for handling an oversized member: %v", err)
+	}
+}
+
+func TestShortParentIdTraceStateValue(t *testing.T) {
+	MinParentIdLength = 8
+	defer func() { MinParentIdLength = 16 }()
+
+	tc, err := GenerateTraceContext("0ba902b7", &TraceStateMember{Key: "vendor1"}, SamplingBehaviorPassThrough)
+	if err != nil {
+		t.Error("Failed to generate trace context:", err)
+	}
+	if value := tc.TraceState.MemberValue("vendor1"); value != "000000000ba902b7" {
+		t.Error("tracestate value not padded on generate:", value)
+	}
+
+	err = tc.Mutate("0aa902b7", SamplingBehaviorPassThrough, &TraceStateMember{Key: "vendor1"})
+	if err != nil {
+		t.Error("Failed to mutate trace context:", err)
+	}
+	if value := tc.TraceState.MemberValue("vendor1"); value != "000000000aa902b7" {
+		t.Error("tracestate value not padded on mutate:", value)
+	}
+}
@@ -0,0 +1,24 @@
+// Package tracecontexttest provides utilities for conformance testing of
+// trace context handling.
+package tracecontexttest
+
+import (
+	"testing"
+
+	tracecontext "github.com/garciasdos/w3c-trace-context"
+)
+
+// AssertRoundTrip parses the traceparent, serializes it, parses the result
+// again and fails the test if parsing fails or the parsed values differ
+func AssertRoundTrip(t testing.TB, traceparent string) {
+	t.Helper()
+
+	stable, err := tracecontext.RoundTripStable(traceparent)
+	if err != nil {
+		t.Errorf("Failed to round-trip traceparent '%s': %v", traceparent, err)
+		return
+	}
+	if !stable {
+		t.Errorf("traceparent '%s' is not stable across a round-trip", traceparent)
+	}
+}
@@ -0,0 +1,18 @@
+package tracecontexttest
+
+import (
+	"testing"
+)
+
+func TestAssertRoundTrip(t *testing.T) {
+	inputs := []string{
+		"00-0af7651916cd43dd8448eb211c80319c-00f067aa0ba902b7-01",
+		"00-0af7651916cd43dd8448eb211c80319c-00f067aa0ba902b7-00",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-b7ad6b7169203331-ff",
+		"01-0af7651916cd43dd8448eb211c80319c-00f067aa0ba902b7-01-123",
+	}
+
+	for _, input := range inputs {
+		AssertRoundTrip(t, input)
+	}
+}
@@ -0,0 +1,18 @@
+// Package traceotel bridges trace context to the OpenTelemetry trace API.
+package traceotel
+
+import (
+	tracecontext "github.com/garciasdos/w3c-trace-context"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// OtelFlags returns the flags of the TraceParent as OpenTelemetry TraceFlags
+func OtelFlags(tp *tracecontext.TraceParent) trace.TraceFlags {
+	return trace.TraceFlags(tp.Flags())
+}
+
+// SetOtelFlags replaces all flags of the TraceParent with the provided
+// OpenTelemetry TraceFlags
+func SetOtelFlags(tp *tracecontext.TraceParent, flags trace.TraceFlags) {
+	tp.SetFlags(byte(flags))
+}
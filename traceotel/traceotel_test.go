@@ -0,0 +1,34 @@
+package traceotel
+
+import (
+	"testing"
+
+	tracecontext "github.com/garciasdos/w3c-trace-context"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestOtelFlags(t *testing.T) {
+	tp, _ := tracecontext.ParseTraceParent("00-0af7651916cd43dd8448eb211c80319c-00f067aa0ba902b7-01")
+
+	if OtelFlags(tp) != trace.FlagsSampled {
+		t.Errorf("Wrong flags %v", OtelFlags(tp))
+	}
+	if !OtelFlags(tp).IsSampled() {
+		t.Error("Sampled flag not mapped")
+	}
+}
+
+func TestSetOtelFlags(t *testing.T) {
+	tp, _ := tracecontext.ParseTraceParent("00-0af7651916cd43dd8448eb211c80319c-00f067aa0ba902b7-00")
+
+	SetOtelFlags(tp, trace.FlagsSampled)
+
+	if !tp.IsSampled() {
+		t.Error("Sampled flag not set")
+	}
+
+	SetOtelFlags(tp, trace.TraceFlags(0))
+	if tp.IsSampled() {
+		t.Error("Sampled flag not cleared")
+	}
+}
@@ -5,12 +5,15 @@ import (
 	"errors"
 	"fmt"
 	"regexp"
+	"strconv"
+	"strings"
 )
 
 type SamplingBehavior uint8
 
 const (
 	FlagSampled                         uint8 = 1
+	FlagRandom                          uint8 = 2
 	HighestSupportedTraceContextVersion uint8 = 0
 
 	// SamplingBehaviorPassThrough leads to sampling decisions from the calling
@@ -38,12 +41,48 @@ var (
 		versionFormat + traceIdFormat + `-` + parentIdFormat + `-` + flagsFormat + `$`)
 )
 
+// MinParentIdLength is the minimum number of hex characters accepted by
+// SetParentId and NewTraceParent. The specification requires 16 characters,
+// which is the default. It may be lowered for non-standard systems using
+// shorter ids, which are left-padded with zeros to stay spec compliant on the
+// wire. Values outside of the range 1 to 16 are clamped to it.
+var MinParentIdLength = 16
+
+// minParentIdLength returns MinParentIdLength clamped to the range 1 to 16
+func minParentIdLength() int {
+	if MinParentIdLength < 1 {
+		return 1
+	}
+	if MinParentIdLength > 16 {
+		return 16
+	}
+	return MinParentIdLength
+}
+
+// ErrInvalidVersion is returned when parsing a traceparent with the invalid
+// version ff. HandleTraceContext restarts the trace in this case.
+var ErrInvalidVersion = errors.New("version 'ff' is invalid")
+
+// OnVersionDowngrade is called with the received version whenever a
+// traceparent of a higher version is downgraded to the highest supported
+// version. It is not called if left nil.
+var OnVersionDowngrade func(received uint8)
+
+// StrictHigherVersion enables the validation of the fields following the flags
+// of a traceparent of a higher version. If enabled, every trailing field must
+// consist of lowercase hex characters, otherwise the traceparent is rejected
+// instead of being downgraded.
+var StrictHigherVersion = false
+
 // TraceParent represents the information contained in the traceparent header
 type TraceParent struct {
 	version  uint8
 	traceId  string
 	parentId string
 	flags    byte
+	// extraFields holds the raw fields following the flags of a downgraded
+	// traceparent of a higher version
+	extraFields string
 }
 
 // ParseTraceParent parses the input string and - on success - returns a
@@ -63,11 +102,15 @@ func ParseTraceParent(s string) (*TraceParent, error) {
 			return nil, errors.New("cannot parse traceparent version")
 		}
 		parsedVersion := uint8(versionByte[0])
+		// Version ff is invalid, also when followed by additional fields
+		if parsedVersion == 255 {
+			return nil, ErrInvalidVersion
+		}
 
 		// If a higher version is detected, the implementation SHOULD try to
 		// parse it by trying the following
 		if parsedVersion > HighestSupportedTraceContextVersion {
-			return parseHigherVersion(s)
+			return parseHigherVersion(s, parsedVersion)
 		}
 
 		return nil, errors.New("traceparent doesn't match the specified pattern")
@@ -80,7 +123,10 @@ func ParseTraceParent(s string) (*TraceParent, error) {
 	}
 	// Version ff is invalid
 	if parsedVersion == 255 {
-		return nil, errors.New("version 'ff' is invalid")
+		return nil, ErrInvalidVersion
+	}
+	if parsedVersion > HighestSupportedTraceContextVersion {
+		return parseHigherVersion(s, parsedVersion)
 	}
 
 	parent.version = uint8(parsedVersion)
@@ -104,9 +150,72 @@ func ParseTraceParent(s string) (*TraceParent, error) {
 	return &parent, nil
 }
 
+// ParseTraceParentBytes parses the input byte slice like ParseTraceParent
+// without requiring the caller to convert it to a string first
+func ParseTraceParentBytes(b []byte) (*TraceParent, error) {
+	tp, ok, err := parseTraceParentVersionZero(b)
+	if !ok {
+		return ParseTraceParent(string(b))
+	}
+	return tp, err
+}
+
+// parseTraceParentVersionZero parses traceparent values of version 00 without
+// the regex overhead of ParseTraceParent. If the returned bool is false, the
+// value has a different format and needs to be parsed by ParseTraceParent.
+func parseTraceParentVersionZero[T ~string | ~[]byte](b T) (*TraceParent, bool, error) {
+	// Headers of a future version may carry additional fields and are
+	// handled by the string based parser
+	if len(b) != 55 || b[2] != '-' || b[35] != '-' || b[52] != '-' ||
+		!isLowerHex(b[0:2]) || !isLowerHex(b[3:35]) ||
+		!isLowerHex(b[36:52]) || !isLowerHex(b[53:55]) {
+		return nil, false, nil
+	}
+
+	// Version ff is invalid
+	parsedVersion := hexToByte(b[0], b[1])
+	if parsedVersion == 255 {
+		return nil, true, ErrInvalidVersion
+	}
+	if parsedVersion > HighestSupportedTraceContextVersion {
+		return nil, false, nil
+	}
+
+	if isZero(b[3:35]) {
+		return nil, true, errors.New("all zero trace id is not allowed")
+	}
+	if isZero(b[36:52]) {
+		return nil, true, errors.New("all zero parent id is not allowed")
+	}
+
+	parent := TraceParent{
+		version:  parsedVersion,
+		traceId:  string(b[3:35]),
+		parentId: string(b[36:52]),
+		flags:    hexToByte(b[53], b[54]),
+	}
+
+	return &parent, true, nil
+}
+
+// RoundTripStable parses the traceparent, serializes it, parses the result
+// again and reports whether both parsed values are equal. An error is returned
+// if either parsing step fails.
+func RoundTripStable(traceparent string) (bool, error) {
+	tp, err := ParseTraceParent(traceparent)
+	if err != nil {
+		return false, err
+	}
+	reparsed, err := ParseTraceParent(tp.String())
+	if err != nil {
+		return false, err
+	}
+	return tp.String() == reparsed.String(), nil
+}
+
 // parseHigherVersion contains the logic to attempt to parse a traceparent that
 // has a version higher than 00.
-func parseHigherVersion(s string) (*TraceParent, error) {
+func parseHigherVersion(s string, receivedVersion uint8) (*TraceParent, error) {
 	// If the size of the header is shorter than 55 characters, the
 	// vendor should not parse the header and should restart the trace.
 	if len(s) < 55 {
@@ -120,6 +229,9 @@ func parseHigherVersion(s string) (*TraceParent, error) {
 		return nil, errors.New("cannot parse trace id")
 	}
 	traceId := s[3:35]
+	if isZero(traceId) {
+		return nil, errors.New("all zero trace id is not allowed")
+	}
 
 	// Parse parent-id (from the second dash at the 35th position through the
 	// next 16 characters). Vendors MUST check that the 16 characters are hex
@@ -128,6 +240,9 @@ func parseHigherVersion(s string) (*TraceParent, error) {
 		return nil, errors.New("cannot parse parent id")
 	}
 	parentId := s[36:52]
+	if isZero(parentId) {
+		return nil, errors.New("all zero parent id is not allowed")
+	}
 
 	// Parse the sampled bit of flags (2 characters from the third dash).
 	if !flagsPattern.MatchString(s[53:55]) {
@@ -145,6 +260,13 @@ func parseHigherVersion(s string) (*TraceParent, error) {
 	}
 	flags := parsedFlags[0]
 
+	if StrictHigherVersion && len(s) > 55 {
+		err = validateExtraFields(s[56:])
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	// Vendors MUST use these fields to construct the new traceparent field
 	// according to the highest version of the specification known to the
 	// implementation (in this specification it is 00).
@@ -154,15 +276,84 @@ func parseHigherVersion(s string) (*TraceParent, error) {
 		parentId: parentId,
 		flags:    flags,
 	}
+	if len(s) > 56 {
+		tp.extraFields = s[56:]
+	}
+
+	if OnVersionDowngrade != nil {
+		OnVersionDowngrade(receivedVersion)
+	}
 
 	return &tp, nil
 }
 
+// validateExtraFields checks that every dash-delimited field of a higher
+// version traceparent following the flags is a non-empty lowercase hex value
+func validateExtraFields(s string) error {
+	for _, field := range strings.Split(s, "-") {
+		if len(field) == 0 || !isLowerHex(field) {
+			return errors.New("cannot parse extra field")
+		}
+	}
+	return nil
+}
+
+// ExtraFields returns the raw dash-delimited fields that followed the flags of
+// a traceparent of a higher version. They are dropped when the traceparent is
+// serialized with the highest supported version.
+func (tp *TraceParent) ExtraFields() string {
+	return tp.extraFields
+}
+
+// Lossy returns true if information was lost when downgrading a traceparent of
+// a higher version, i.e. if it carried extra fields
+func (tp *TraceParent) Lossy() bool {
+	return tp.extraFields != ""
+}
+
 // IsSampled returns true if the sampled flag in the TraceParent is set
 func (p *TraceParent) IsSampled() bool {
 	return p.flags&FlagSampled != 0
 }
 
+// IsRandom returns true if the random flag in the TraceParent is set, which
+// signals that the right-most 7 bytes of the trace id are random
+func (tp *TraceParent) IsRandom() bool {
+	return tp.flags&FlagRandom != 0
+}
+
+// RandomnessValue returns the randomness value used for consistent sampling,
+// which is the right-most 7 bytes of the trace id interpreted as a 56 bit
+// unsigned integer. The value is only guaranteed to be random if IsRandom
+// returns true.
+func (tp *TraceParent) RandomnessValue() uint64 {
+	if len(tp.traceId) != 32 {
+		return 0
+	}
+	r, _ := strconv.ParseUint(tp.traceId[18:], 16, 64)
+	return r
+}
+
+// HasSufficientEntropy returns false if the trace id follows an obvious
+// non-random pattern, i.e. it consists of a single repeated hex character or
+// of an ascending or descending hex sequence like 0123456789abcdef0123...
+// This is meant to catch broken id generators and is no proof of randomness.
+func (tp *TraceParent) HasSufficientEntropy() bool {
+	id := tp.traceId
+	if len(id) != 32 || !isLowerHex(id) {
+		return false
+	}
+
+	same, ascending, descending := true, true, true
+	for i := 1; i < len(id); i++ {
+		prev, cur := hexNibble(id[i-1]), hexNibble(id[i])
+		same = same && cur == prev
+		ascending = ascending && cur == (prev+1)&0x0f
+		descending = descending && cur == (prev-1)&0x0f
+	}
+	return !same && !ascending && !descending
+}
+
 // SetSampled updates the sampled flag with the given value
 func (tp *TraceParent) SetSampled(s bool) {
 	if s {
@@ -172,20 +363,72 @@ func (tp *TraceParent) SetSampled(s bool) {
 	}
 }
 
+// MergeFlags merges the provided flags into the TraceParent flags using a
+// bitwise OR, so that a flag set by either side remains set
+func (tp *TraceParent) MergeFlags(other byte) {
+	tp.flags |= other
+}
+
+// ClampToSampled clears all flags except the sampled flag, e.g. to strip
+// experimental flag bits before forwarding the traceparent
+func (tp *TraceParent) ClampToSampled() {
+	tp.flags &= FlagSampled
+}
+
 // NewTraceParent generates a new TraceParent based on the provided values.
 // If the values don't match the correct format, an error is returned
 func NewTraceParent(traceId string, parentId string) (*TraceParent, error) {
 	tp := TraceParent{}
-	err := tp.SetTraceId(traceId)
+	err := tp.SetIDs(traceId, parentId)
 	if err != nil {
 		return nil, err
 	}
-	err = tp.SetParentId(parentId)
+
+	return &tp, nil
+}
+
+// SetIDs updates the trace id and parent id together. Both values are
+// validated before either is applied, so the TraceParent is left unchanged if
+// one of them is invalid.
+func (tp *TraceParent) SetIDs(traceId string, parentId string) error {
+	validated := TraceParent{}
+	err := validated.SetTraceId(traceId)
 	if err != nil {
-		return nil, err
+		return err
+	}
+	err = validated.SetParentId(parentId)
+	if err != nil {
+		return err
 	}
 
-	return &tp, nil
+	tp.traceId = validated.traceId
+	tp.parentId = validated.parentId
+	return nil
+}
+
+// GenerateTraceParentHeader returns a ready-to-send traceparent header value
+// with a random trace id and parent id. The sampled flag is set in accordance
+// with the selected sampling behavior.
+func GenerateTraceParentHeader(sampling SamplingBehavior) (string, error) {
+	traceId, err := idGenerator.NewTraceID()
+	if err != nil {
+		return "", err
+	}
+	parentId, err := idGenerator.NewSpanID()
+	if err != nil {
+		return "", err
+	}
+
+	tp, err := NewTraceParent(traceId, parentId)
+	if err != nil {
+		return "", err
+	}
+	err = tp.applySamplingBehavior(sampling)
+	if err != nil {
+		return "", err
+	}
+
+	return tp.String(), nil
 }
 
 func (tp *TraceParent) ParentId() string {
@@ -200,9 +443,28 @@ func (tp *TraceParent) Version() uint8 {
 	return tp.version
 }
 
+func (tp *TraceParent) Flags() byte {
+	return tp.flags
+}
+
+// SetFlags replaces all flags with the given value
+func (tp *TraceParent) SetFlags(flags byte) {
+	tp.flags = flags
+}
+
+// SetParentId updates the parent id with the given value. Parent ids shorter
+// than 16 hex characters are only accepted if MinParentIdLength was lowered
+// and are left-padded with zeros. A short parent id consisting only of zeros
+// is rejected, since padding it would produce an invalid parent id.
 func (tp *TraceParent) SetParentId(parentId string) error {
 	if !parentIdPattern.MatchString(parentId) {
-		return errors.New("parentId doesn't match the specified pattern")
+		if len(parentId) < minParentIdLength() || len(parentId) >= 16 || !isLowerHex(parentId) {
+			return errors.New("parentId doesn't match the specified pattern")
+		}
+		if isZero(parentId) {
+			return errors.New("all zero parent id is not allowed")
+		}
+		parentId = strings.Repeat("0", 16-len(parentId)) + parentId
 	}
 	tp.parentId = parentId
 	return nil
@@ -216,15 +478,88 @@ func (tp *TraceParent) SetTraceId(traceId string) error {
 	return nil
 }
 
+// TraceIDUUID returns the trace id formatted like a UUID with dashes, e.g.
+// 0af76519-16cd-43dd-8448-eb211c80319c, for display purposes
+func (tp *TraceParent) TraceIDUUID() string {
+	if len(tp.traceId) != 32 {
+		return tp.traceId
+	}
+	return tp.traceId[0:8] + "-" + tp.traceId[8:12] + "-" + tp.traceId[12:16] + "-" +
+		tp.traceId[16:20] + "-" + tp.traceId[20:32]
+}
+
+// SetTraceIDUUID updates the trace id with a value formatted like a UUID with
+// dashes. As UUIDs are case-insensitive, uppercase hex characters are accepted
+// and converted to lowercase.
+func (tp *TraceParent) SetTraceIDUUID(s string) error {
+	if len(s) != 36 || s[8] != '-' || s[13] != '-' || s[18] != '-' || s[23] != '-' {
+		return errors.New("traceId doesn't match the UUID format")
+	}
+	traceId := s[0:8] + s[9:13] + s[14:18] + s[19:23] + s[24:36]
+	return tp.SetTraceId(strings.ToLower(traceId))
+}
+
 // String returns the string representation of the TraceParent
 func (tp *TraceParent) String() string {
+	return tp.StringWithVersion(tp.version)
+}
+
+// StringWithVersion returns the string representation of the TraceParent with
+// the provided version instead of its own. This is meant for interop tests
+// that verify how peers handle versions other than 00.
+func (tp *TraceParent) StringWithVersion(v uint8) string {
 	return fmt.Sprintf("%02x-%s-%s-%02x",
-		tp.version,
+		v,
 		tp.traceId,
 		tp.parentId,
 		tp.flags)
 }
 
+// AppendFormat appends the string representation of the TraceParent to b and
+// returns the extended buffer. Unlike String, it doesn't allocate if b has
+// enough capacity.
+func (tp *TraceParent) AppendFormat(b []byte) []byte {
+	b = appendHexByte(b, tp.version)
+	b = append(b, '-')
+	b = append(b, tp.traceId...)
+	b = append(b, '-')
+	b = append(b, tp.parentId...)
+	b = append(b, '-')
+	return appendHexByte(b, tp.flags)
+}
+
+// validate checks that the TraceParent matches the format specification
+func (tp *TraceParent) validate() error {
+	if tp.version == 255 {
+		return ErrInvalidVersion
+	}
+	if !traceIdPattern.MatchString(tp.traceId) {
+		return errors.New("traceId doesn't match the specified pattern")
+	}
+	if tp.traceId == "00000000000000000000000000000000" {
+		return errors.New("all zero trace id is not allowed")
+	}
+	if !parentIdPattern.MatchString(tp.parentId) {
+		return errors.New("parentId doesn't match the specified pattern")
+	}
+	if tp.parentId == "0000000000000000" {
+		return errors.New("all zero parent id is not allowed")
+	}
+	return nil
+}
+
+// ChainSampling composes multiple sampling behaviors. The first behavior that
+// isn't SamplingBehaviorPassThrough wins. If all behaviors pass through, or
+// none are provided, SamplingBehaviorPassThrough is returned.
+func ChainSampling(behaviors ...SamplingBehavior) SamplingBehavior {
+	for _, b := range behaviors {
+		if b != SamplingBehaviorPassThrough {
+			return b
+		}
+	}
+	return SamplingBehaviorPassThrough
+}
+
 // applySamplingBehavior applies the selected sampling behavior to the TraceParent
 func (tp *TraceParent) applySamplingBehavior(sampling SamplingBehavior) error {
 	switch sampling {
@@ -31,9 +31,15 @@ func TestParseTraceParentZeroTraceParent(t *testing.T) {
 func TestParseTraceParentFFVersion(t *testing.T) {
 	_, err := ParseTraceParent("ff-0af7651916cd43dd8448eb211c80319c-00f067aa0ba902b7-01")
 
-	if err == nil {
+	if err != ErrInvalidVersion {
 		t.Error("Incorrectly parsed ff version")
 	}
+
+	_, err = ParseTraceParent("ff-0af7651916cd43dd8448eb211c80319c-00f067aa0ba902b7-01-00")
+
+	if err != ErrInvalidVersion {
+		t.Error("Incorrectly parsed ff version with additional field")
+	}
 }
 
 func TestParseTraceParentVersionOne(t *testing.T) {
@@ -95,3 +101,448 @@ func TestTraceParentIsSampled(t *testing.T) {
 	}
 
 }
+
+func TestTraceParentMergeFlags(t *testing.T) {
+	tp, _ := ParseTraceParent("00-0af7651916cd43dd8448eb211c80319c-00f067aa0ba902b7-00")
+	sampled, _ := ParseTraceParent("00-0af7651916cd43dd8448eb211c80319c-00f067aa0ba902b7-01")
+
+	tp.MergeFlags(sampled.flags)
+
+	if !tp.IsSampled() {
+		t.Error("Sampled flag not merged")
+	}
+}
+
+func TestTraceParentClampToSampled(t *testing.T) {
+	tp, _ := ParseTraceParent("00-0af7651916cd43dd8448eb211c80319c-00f067aa0ba902b7-07")
+
+	tp.ClampToSampled()
+
+	if tp.Flags() != FlagSampled {
+		t.Errorf("Wrong flags %02x", tp.Flags())
+	}
+}
+
+func TestParseTraceParentBytes(t *testing.T) {
+	inputs := []string{
+		"00-0af7651916cd43dd8448eb211c80319c-00f067aa0ba902b7-01",
+		"00-0af7651916cd43dd8448eb211c80319c-00f067aa0ba902b7-00",
+		"01-0af7651916cd43dd8448eb211c80319c-00f067aa0ba902b7-01-123",
+		"00-00000000000000000000000000000000-0000000000000001-00",
+		"00-00000000000000000000000000000001-0000000000000000-00",
+		"ff-0af7651916cd43dd8448eb211c80319c-00f067aa0ba902b7-01",
+		"00-0AF7651916CD43DD8448EB211C80319C-00f067aa0ba902b7-01",
+		"01-illegal",
+		"",
+	}
+
+	for _, input := range inputs {
+		expected, expectedErr := ParseTraceParent(input)
+		tp, err := ParseTraceParentBytes([]byte(input))
+
+		if (err == nil) != (expectedErr == nil) {
+			t.Errorf("Error mismatch for '%s': %v, %v", input, err, expectedErr)
+			continue
+		}
+		if err == nil && *tp != *expected {
+			t.Errorf("Result mismatch for '%s': %v, %v", input, tp, expected)
+		}
+	}
+}
+
+func BenchmarkParseTraceParent(b *testing.B) {
+	input := []byte("00-0af7651916cd43dd8448eb211c80319c-00f067aa0ba902b7-01")
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		ParseTraceParent(string(input))
+	}
+}
+
+func BenchmarkParseTraceParentBytes(b *testing.B) {
+	input := []byte("00-0af7651916cd43dd8448eb211c80319c-00f067aa0ba902b7-01")
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		ParseTraceParentBytes(input)
+	}
+}
+
+func TestGenerateTraceParentHeader(t *testing.T) {
+	for _, sampling := range []SamplingBehavior{SamplingBehaviorAlwaysSampled, SamplingBehaviorNeverSampled} {
+		s, err := GenerateTraceParentHeader(sampling)
+		if err != nil {
+			t.Error("Failed to generate traceparent header:", err)
+		}
+		if len(s) != 55 {
+			t.Errorf("Incorrect length %d of generated header", len(s))
+		}
+
+		tp, err := ParseTraceParent(s)
+		if err != nil {
+			t.Error("Could not parse generated traceparent header:", err)
+			continue
+		}
+		if tp.IsSampled() != (sampling == SamplingBehaviorAlwaysSampled) {
+			t.Error("Sampling flag not set right")
+		}
+	}
+}
+
+func TestOnVersionDowngrade(t *testing.T) {
+	var received []uint8
+	OnVersionDowngrade = func(v uint8) {
+		received = append(received, v)
+	}
+	defer func() { OnVersionDowngrade = nil }()
+
+	tp, err := ParseTraceParent("02-0af7651916cd43dd8448eb211c80319c-00f067aa0ba902b7-01")
+	if err != nil {
+		t.Error("Could not parse valid future version:", err)
+	}
+	if tp.Version() != HighestSupportedTraceContextVersion {
+		t.Error("version wasn't downgraded")
+	}
+	if len(received) != 1 || received[0] != 2 {
+		t.Errorf("Hook not called with received version: %v", received)
+	}
+
+	received = nil
+	ParseTraceParent("00-0af7651916cd43dd8448eb211c80319c-00f067aa0ba902b7-01")
+	if len(received) != 0 {
+		t.Error("Hook called without downgrade")
+	}
+}
+
+func TestSetParentIdStrictLength(t *testing.T) {
+	tp, _ := NewTraceParent("0af7651916cd43dd8448eb211c80319c", "00f067aa0ba902b7")
+
+	if err := tp.SetParentId("0ba902b7"); err == nil {
+		t.Error("Short parent id accepted in strict mode")
+	}
+	if tp.ParentId() != "00f067aa0ba902b7" {
+		t.Error("parent id changed")
+	}
+}
+
+func TestSetParentIdRelaxedLength(t *testing.T) {
+	MinParentIdLength = 8
+	defer func() { MinParentIdLength = 16 }()
+
+	tp, err := NewTraceParent("0af7651916cd43dd8448eb211c80319c", "0ba902b7")
+	if err != nil {
+		t.Error("Short parent id not accepted in relaxed mode:", err)
+	}
+	if tp.ParentId() != "000000000ba902b7" {
+		t.Error("parent id not padded", tp.ParentId())
+	}
+	if tp.String() != "00-0af7651916cd43dd8448eb211c80319c-000000000ba902b7-00" {
+		t.Error("traceparent not spec compliant", tp.String())
+	}
+
+	if err := tp.SetParentId("02b7"); err == nil {
+		t.Error("Parent id shorter than configured length accepted")
+	}
+	if err := tp.SetParentId("0ba902bz"); err == nil {
+		t.Error("Non hex parent id accepted")
+	}
+	if err := tp.SetParentId("00000000"); err == nil {
+		t.Error("Short all zero parent id accepted")
+	}
+}
+
+func TestMinParentIdLengthClamped(t *testing.T) {
+	tp, _ := NewTraceParent("0af7651916cd43dd8448eb211c80319c", "00f067aa0ba902b7")
+	defer func() { MinParentIdLength = 16 }()
+
+	MinParentIdLength = 0
+	if err := tp.SetParentId(""); err == nil {
+		t.Error("Empty parent id accepted")
+	}
+	if err := tp.SetParentId("0"); err == nil {
+		t.Error("All zero parent id accepted")
+	}
+	if err := tp.SetParentId("1"); err != nil || tp.ParentId() != "0000000000000001" {
+		t.Error("Single character parent id not accepted:", err)
+	}
+
+	MinParentIdLength = 32
+	if err := tp.SetParentId("00f067aa0ba902b7"); err != nil {
+		t.Error("Spec compliant parent id rejected:", err)
+	}
+}
+
+func TestRandomnessValue(t *testing.T) {
+	cases := map[string]uint64{
+		"02-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-03": 0xce929d0e0e4736,
+		"02-0af7651916cd43dd8448eb211c80319c-00f067aa0ba902b7-02": 0x48eb211c80319c,
+		"02-0af7651916cd43dd80ffffffffffffff-00f067aa0ba902b7-02": 0xffffffffffffff,
+	}
+
+	for input, expected := range cases {
+		tp, err := ParseTraceParent(input)
+		if err != nil {
+			t.Error("Failed to parse traceparent:", err)
+			continue
+		}
+		if !tp.IsRandom() {
+			t.Error("random flag not detected")
+		}
+		if tp.RandomnessValue() != expected {
+			t.Errorf("Wrong randomness value %x for %s", tp.RandomnessValue(), input)
+		}
+	}
+}
+
+func TestParseTraceParentFutureVersionMissingFlags(t *testing.T) {
+	inputs := []string{
+		"01-0af7651916cd43dd8448eb211c80319c-00f067aa0ba902b7-",
+		"01-0af7651916cd43dd8448eb211c80319c-00f067aa0ba902b7-0",
+	}
+
+	for _, input := range inputs {
+		tp, err := ParseTraceParent(input)
+		if err == nil || tp != nil {
+			t.Errorf("Incorrectly parsed traceparent of length %d", len(input))
+		}
+		tp, err = ParseTraceParentBytes([]byte(input))
+		if err == nil || tp != nil {
+			t.Errorf("Incorrectly parsed traceparent bytes of length %d", len(input))
+		}
+	}
+}
+
+func TestTraceParentStringWithVersion(t *testing.T) {
+	tp, _ := NewTraceParent("0af7651916cd43dd8448eb211c80319c", "00f067aa0ba902b7")
+	tp.SetSampled(true)
+
+	s := tp.StringWithVersion(2)
+	if s != "02-0af7651916cd43dd8448eb211c80319c-00f067aa0ba902b7-01" {
+		t.Errorf("Wrong string value returned: '%s'", s)
+	}
+
+	newTp, err := ParseTraceParent(s)
+	if err != nil {
+		t.Error("Could not parse generated traceparent:", err)
+	}
+	if newTp.Version() != HighestSupportedTraceContextVersion {
+		t.Error("version wasn't downgraded")
+	}
+	if newTp.String() != tp.String() {
+		t.Errorf("traceparent not equal after downgrade: '%s'", newTp.String())
+	}
+}
+
+func TestRoundTripStable(t *testing.T) {
+	inputs := []string{
+		"00-0af7651916cd43dd8448eb211c80319c-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-b7ad6b7169203331-ff",
+		"02-0af7651916cd43dd8448eb211c80319c-00f067aa0ba902b7-01",
+	}
+
+	for _, input := range inputs {
+		stable, err := RoundTripStable(input)
+		if err != nil || !stable {
+			t.Errorf("traceparent '%s' not stable: %v", input, err)
+		}
+	}
+
+	if _, err := RoundTripStable("01-illegal"); err == nil {
+		t.Error("Invalid traceparent didn't cause an error")
+	}
+}
+
+func TestTraceParentSetIDs(t *testing.T) {
+	tp, _ := NewTraceParent("0af7651916cd43dd8448eb211c80319c", "00f067aa0ba902b7")
+
+	err := tp.SetIDs("4bf92f3577b34da6a3ce929d0e0e4736", "b7ad6b7169203331")
+	if err != nil {
+		t.Error("Failed to set ids:", err)
+	}
+	if tp.TraceId() != "4bf92f3577b34da6a3ce929d0e0e4736" || tp.ParentId() != "b7ad6b7169203331" {
+		t.Error("ids not set")
+	}
+}
+
+func TestTraceParentSetIDsInvalidParentId(t *testing.T) {
+	tp, _ := NewTraceParent("0af7651916cd43dd8448eb211c80319c", "00f067aa0ba902b7")
+
+	err := tp.SetIDs("4bf92f3577b34da6a3ce929d0e0e4736", "invalid")
+	if err == nil {
+		t.Error("Invalid parent id didn't cause an error")
+	}
+	if tp.TraceId() != "0af7651916cd43dd8448eb211c80319c" || tp.ParentId() != "00f067aa0ba902b7" {
+		t.Error("ids were changed")
+	}
+}
+
+func TestParseHigherVersionZeroIds(t *testing.T) {
+	inputs := []string{
+		"01-00000000000000000000000000000000-00f067aa0ba902b7-01",
+		"01-0af7651916cd43dd8448eb211c80319c-0000000000000000-01",
+		"cc-00000000000000000000000000000000-00f067aa0ba902b7-01-extra",
+		"cc-0af7651916cd43dd8448eb211c80319c-0000000000000000-01-extra",
+	}
+
+	for _, input := range inputs {
+		if _, err := ParseTraceParent(input); err == nil {
+			t.Errorf("Traceparent with all zero id '%s' parsed", input)
+		}
+		if _, err := ParseTraceParentBytes([]byte(input)); err == nil {
+			t.Errorf("Traceparent bytes with all zero id '%s' parsed", input)
+		}
+	}
+}
+
+func TestTraceParentLossy(t *testing.T) {
+	tp, err := ParseTraceParent("01-0af7651916cd43dd8448eb211c80319c-00f067aa0ba902b7-01-extra")
+	if err != nil {
+		t.Error("Could not parse valid future version:", err)
+	}
+	if !tp.Lossy() || tp.ExtraFields() != "extra" {
+		t.Errorf("Lost extra fields not reported: '%s'", tp.ExtraFields())
+	}
+
+	tp, _ = ParseTraceParent("00-0af7651916cd43dd8448eb211c80319c-00f067aa0ba902b7-01")
+	if tp.Lossy() || tp.ExtraFields() != "" {
+		t.Error("Lossy reported for version 00")
+	}
+}
+
+func TestStrictHigherVersion(t *testing.T) {
+	StrictHigherVersion = true
+	defer func() { StrictHigherVersion = false }()
+
+	valid := []string{
+		"01-0af7651916cd43dd8448eb211c80319c-00f067aa0ba902b7-01",
+		"01-0af7651916cd43dd8448eb211c80319c-00f067aa0ba902b7-01-0a",
+		"01-0af7651916cd43dd8448eb211c80319c-00f067aa0ba902b7-01-0a-ff00",
+	}
+	for _, input := range valid {
+		if _, err := ParseTraceParent(input); err != nil {
+			t.Errorf("Conforming traceparent '%s' rejected: %v", input, err)
+		}
+	}
+
+	invalid := []string{
+		"01-0af7651916cd43dd8448eb211c80319c-00f067aa0ba902b7-01-",
+		"01-0af7651916cd43dd8448eb211c80319c-00f067aa0ba902b7-01-extra",
+		"01-0af7651916cd43dd8448eb211c80319c-00f067aa0ba902b7-01-0a--0b",
+		"01-0af7651916cd43dd8448eb211c80319c-00f067aa0ba902b7-01-0A",
+	}
+	for _, input := range invalid {
+		if _, err := ParseTraceParent(input); err == nil {
+			t.Errorf("Non-conforming traceparent '%s' accepted", input)
+		}
+	}
+}
+
+func TestTraceIDUUID(t *testing.T) {
+	tp, _ := ParseTraceParent("00-0af7651916cd43dd8448eb211c80319c-00f067aa0ba902b7-01")
+
+	uuid := tp.TraceIDUUID()
+	if uuid != "0af76519-16cd-43dd-8448-eb211c80319c" {
+		t.Errorf("Wrong UUID returned: '%s'", uuid)
+	}
+
+	err := tp.SetTraceIDUUID("4BF92F35-77B3-4DA6-A3CE-929D0E0E4736")
+	if err != nil {
+		t.Error("Failed to set UUID trace id:", err)
+	}
+	if tp.TraceId() != "4bf92f3577b34da6a3ce929d0e0e4736" {
+		t.Errorf("Wrong trace id: '%s'", tp.TraceId())
+	}
+
+	tp.SetTraceIDUUID(uuid)
+	if tp.TraceId() != "0af7651916cd43dd8448eb211c80319c" {
+		t.Errorf("UUID round trip failed: '%s'", tp.TraceId())
+	}
+}
+
+func TestSetTraceIDUUIDInvalid(t *testing.T) {
+	tp, _ := ParseTraceParent("00-0af7651916cd43dd8448eb211c80319c-00f067aa0ba902b7-01")
+	inputs := []string{
+		"",
+		"0af7651916cd43dd8448eb211c80319c",
+		"0af76519-16cd-43dd-8448eb211c80319c-",
+		"0af76519-16cd-43dd-8448-eb211c80319g",
+	}
+
+	for _, input := range inputs {
+		if err := tp.SetTraceIDUUID(input); err == nil {
+			t.Errorf("Invalid UUID '%s' accepted", input)
+		}
+	}
+	if tp.TraceId() != "0af7651916cd43dd8448eb211c80319c" {
+		t.Error("trace id was changed")
+	}
+}
+
+func TestTraceParentAppendFormat(t *testing.T) {
+	inputs := []string{
+		"00-0af7651916cd43dd8448eb211c80319c-00f067aa0ba902b7-01",
+		"00-0af7651916cd43dd8448eb211c80319c-00f067aa0ba902b7-ff",
+		"cc-0af7651916cd43dd8448eb211c80319c-00f067aa0ba902b7-0a-extra",
+	}
+
+	for _, input := range inputs {
+		tp, _ := ParseTraceParent(input)
+		b := tp.AppendFormat([]byte("prefix:"))
+		if string(b) != "prefix:"+tp.String() {
+			t.Errorf("Wrong bytes appended: '%s'", b)
+		}
+	}
+}
+
+func BenchmarkTraceParentString(b *testing.B) {
+	tp, _ := ParseTraceParent("00-0af7651916cd43dd8448eb211c80319c-00f067aa0ba902b7-01")
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		_ = tp.String()
+	}
+}
+
+func BenchmarkTraceParentAppendFormat(b *testing.B) {
+	tp, _ := ParseTraceParent("00-0af7651916cd43dd8448eb211c80319c-00f067aa0ba902b7-01")
+	buf := make([]byte, 0, 55)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		buf = tp.AppendFormat(buf[:0])
+	}
+}
+
+func TestHasSufficientEntropy(t *testing.T) {
+	tp, _ := ParseTraceParent("00-0af7651916cd43dd8448eb211c80319c-00f067aa0ba902b7-01")
+	if !tp.HasSufficientEntropy() {
+		t.Error("Random trace id reported without sufficient entropy")
+	}
+
+	inputs := []string{
+		"11111111111111111111111111111111",
+		"0123456789abcdef0123456789abcdef",
+		"fedcba9876543210fedcba9876543210",
+		"456789abcdef0123456789abcdef0123",
+	}
+	for _, input := range inputs {
+		tp.SetTraceId(input)
+		if tp.HasSufficientEntropy() {
+			t.Errorf("Trace id '%s' reported with sufficient entropy", input)
+		}
+	}
+}
+
+func TestChainSampling(t *testing.T) {
+	cases := []struct {
+		chain    []SamplingBehavior
+		expected SamplingBehavior
+	}{
+		{[]SamplingBehavior{SamplingBehaviorPassThrough, SamplingBehaviorAlwaysSampled}, SamplingBehaviorAlwaysSampled},
+		{[]SamplingBehavior{SamplingBehaviorPassThrough, SamplingBehaviorNeverSampled, SamplingBehaviorAlwaysSampled}, SamplingBehaviorNeverSampled},
+		{[]SamplingBehavior{SamplingBehaviorPassThrough, SamplingBehaviorPassThrough}, SamplingBehaviorPassThrough},
+		{nil, SamplingBehaviorPassThrough},
+	}
+
+	for _, c := range cases {
+		if b := ChainSampling(c.chain...); b != c.expected {
+			t.Errorf("Wrong behavior %d for chain %v", b, c.chain)
+		}
+	}
+}
@@ -2,19 +2,53 @@ package tracecontext
 
 import (
 	"errors"
+	"fmt"
+	"io"
 	"regexp"
 	"strings"
 )
 
+// MaxMembers is the maximum number of list-members in a tracestate
+const MaxMembers = 32
+
+// MaxMemberLength is the maximum length of a serialized key=value list-member
+const MaxMemberLength = 256
+
+// ErrMemberTooLong is returned when adding a member whose serialized
+// key=value form exceeds MaxMemberLength characters
+var ErrMemberTooLong = errors.New("member exceeds 256 characters")
+
 var (
-	keyFormat     = `[a-z0-9][a-z0-9_\-\*\/@]{0,255}`
-	keyPattern    = regexp.MustCompile(`^` + keyFormat + `$`)
-	valueFormat   = `[\x20-\x2b\x2d-\x3c\x3e-\x7e]{0,255}[\x21-\x2b\x2d-\x3c\x3e-\x7e]`
-	valuePattern  = regexp.MustCompile(`^` + valueFormat + `$`)
-	memberFormat  = `\s*(` + keyFormat + `)=(` + valueFormat + `)\s*`
-	memberPattern = regexp.MustCompile(`^` + memberFormat + `$`)
+	keyFormat    = `[a-z0-9][a-z0-9_\-\*\/@]{0,255}`
+	keyPattern   = regexp.MustCompile(`^` + keyFormat + `$`)
+	valueFormat  = `[\x20-\x2b\x2d-\x3c\x3e-\x7e]{0,255}[\x21-\x2b\x2d-\x3c\x3e-\x7e]`
+	valuePattern = regexp.MustCompile(`^` + valueFormat + `$`)
 )
 
+// MaxTraceStateHeaderBytes limits the size of tracestate header values that are
+// parsed, to protect against huge headers. Larger values are rejected with
+// ErrTraceStateTooLarge. A value of 0 or less disables the limit.
+var MaxTraceStateHeaderBytes = 8192
+
+// ErrTraceStateTooLarge is returned when parsing a tracestate header value
+// that exceeds MaxTraceStateHeaderBytes
+var ErrTraceStateTooLarge = errors.New("tracestate exceeds the maximum size")
+
+// ErrTruncatedTraceState is returned when parsing a tracestate header value
+// whose last list-member is incomplete, e.g. "a=1,b=", and whose length is
+// within MaxMemberLength bytes of MaxTraceStateHeaderBytes, which indicates
+// that the value was cut off by an intermediary enforcing a size limit
+var ErrTruncatedTraceState = errors.New("tracestate is truncated")
+
+// LowercaseKeys enables a lenient parsing mode for non-conforming peers, in
+// which tracestate keys are lowercased before they are validated and stored
+var LowercaseKeys = false
+
+// OnMutate is called whenever TraceState.Mutate adds or replaces a member,
+// e.g. for audit logging. oldValue is empty if the key was added. It is not
+// called if left nil.
+var OnMutate func(key, oldValue, newValue string)
+
 // TraceState represents the information contained in the tracestate header
 type TraceState struct {
 	Members []*TraceStateMember
@@ -29,15 +63,85 @@ type TraceStateMember struct {
 // ParseTraceState parses the provided string and - on success - returns a
 // TraceState object
 func ParseTraceState(s string) (*TraceState, error) {
-	candidates := strings.Split(s, ",")
+	return parseTraceState(s, nil, nil, 0)
+}
 
-	traceState := TraceState{}
+// ParseTraceStateN parses the provided string like ParseTraceState, but
+// retains at most maxMembers list-members. Members to the right of the limit
+// are discarded without being parsed. A maxMembers value of 0 or less disables
+// the limit.
+func ParseTraceStateN(s string, maxMembers int) (*TraceState, error) {
+	return parseTraceState(s, nil, nil, maxMembers)
+}
+
+// ParseTraceStateReader reads a tracestate header value from the reader and
+// parses it like ParseTraceState. At most maxBytes bytes are read, so that
+// untrusted sources cannot cause unbounded memory use. ErrTraceStateTooLarge
+// is returned if the value exceeds the limit.
+func ParseTraceStateReader(r io.Reader, maxBytes int) (*TraceState, error) {
+	b, err := io.ReadAll(io.LimitReader(r, int64(maxBytes)+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(b) > maxBytes {
+		return nil, ErrTraceStateTooLarge
+	}
+	return ParseTraceState(string(b))
+}
+
+// parseTraceState parses the provided string. The scratch slice is used to
+// split the string into member candidates if it is not nil, and the buffer
+// holds the parsed members if it is not nil, so that a Parser can reuse both
+// across calls. The buffer is only used for up to MaxMembers members, so that
+// it doesn't grow with oversized headers. At most maxMembers members are
+// retained if it is greater than 0.
+func parseTraceState(s string, scratch *[]string, buffer *[]TraceStateMember, maxMembers int) (*TraceState, error) {
+	// Reject oversized input before doing any further work
+	if MaxTraceStateHeaderBytes > 0 && len(s) > MaxTraceStateHeaderBytes {
+		return nil, ErrTraceStateTooLarge
+	}
+
+	var candidates []string
+	if scratch != nil {
+		candidates = appendSplit((*scratch)[:0], s, ',')
+		*scratch = candidates
+	} else {
+		candidates = appendSplit(nil, s, ',')
+	}
+
+	n := 0
 	for _, candidate := range candidates {
+		if len(candidate) > 0 {
+			n++
+		}
+	}
+	if maxMembers > 0 && n > maxMembers {
+		n = maxMembers
+	}
+	var members []TraceStateMember
+	if buffer != nil && n <= MaxMembers {
+		if cap(*buffer) < n {
+			*buffer = make([]TraceStateMember, MaxMembers)
+		}
+		members = (*buffer)[:n]
+	} else {
+		members = make([]TraceStateMember, n)
+	}
+
+	traceState := TraceState{}
+	for i, candidate := range candidates {
 		if len(candidate) == 0 {
 			continue
 		}
-		member, err := parseMember(candidate)
+		if len(traceState.Members) == n {
+			break
+		}
+		member := &members[len(traceState.Members)]
+		err := parseMember(candidate, member)
 		if err != nil {
+			if i == len(candidates)-1 && isNearSizeLimit(s) && isTruncatedMember(candidate) {
+				return nil, ErrTruncatedTraceState
+			}
 			return nil, err
 		}
 		traceState.Members = append(traceState.Members, member)
@@ -46,41 +150,190 @@ func ParseTraceState(s string) (*TraceState, error) {
 	return &traceState, nil
 }
 
-func parseMember(s string) (*TraceStateMember, error) {
-	matches := memberPattern.FindStringSubmatch(s)
-	if len(matches) != 3 {
-		return nil, errors.New("invalid number of matches")
+// isNearSizeLimit returns true if the tracestate header value is within
+// MaxMemberLength bytes of MaxTraceStateHeaderBytes, so that an incomplete
+// last list-member is likely the result of truncation
+func isNearSizeLimit(s string) bool {
+	return MaxTraceStateHeaderBytes > 0 && len(s) >= MaxTraceStateHeaderBytes-MaxMemberLength
+}
+
+// isTruncatedMember returns true if the list-member consists of a valid key
+// that is not followed by a value, i.e. "key" or "key=", as left behind when a
+// header value is cut off
+func isTruncatedMember(s string) bool {
+	key, value, _ := strings.Cut(strings.Trim(s, " \t\n\f\r"), "=")
+	if LowercaseKeys {
+		key = strings.ToLower(key)
+	}
+	return value == "" && isValidKey(key)
+}
+
+// NormalizeTraceStateString parses the tracestate header value and returns it
+// in its canonical form without optional whitespace and empty members, so
+// that it can be compared with other tracestate strings
+func NormalizeTraceStateString(s string) (string, error) {
+	ts, err := ParseTraceState(s)
+	if err != nil {
+		return "", err
 	}
+	return ts.String(), nil
+}
 
-	member := TraceStateMember{
-		Key:   matches[1],
-		Value: matches[2],
+// ValidateTraceStateString validates every list-member of the tracestate
+// header value and returns one error per malformed member, including the
+// index of the member in the list. Unlike ParseTraceState, it doesn't stop at
+// the first malformed member. The returned slice is empty if the value is
+// valid.
+func ValidateTraceStateString(s string) []error {
+	if MaxTraceStateHeaderBytes > 0 && len(s) > MaxTraceStateHeaderBytes {
+		return []error{ErrTraceStateTooLarge}
 	}
 
-	return &member, nil
+	var errs []error
+	for i, candidate := range appendSplit(nil, s, ',') {
+		if len(candidate) == 0 {
+			continue
+		}
+		var member TraceStateMember
+		err := parseMember(candidate, &member)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("member %d: %w", i, err))
+		}
+	}
+	return errs
 }
 
-// Mutate will add a new member to beginning of the list and - if the key is
-// already present - remove the old entry
-func (ts *TraceState) Mutate(member TraceStateMember) error {
+// parseMember parses a single list-member with optional surrounding
+// whitespace into member
+func parseMember(s string, member *TraceStateMember) error {
+	if _, value, found := strings.Cut(s, "="); found {
+		err := checkASCII(value)
+		if err != nil {
+			return err
+		}
+	}
+
+	key, value, found := strings.Cut(strings.Trim(s, " \t\n\f\r"), "=")
+	if LowercaseKeys {
+		key = strings.ToLower(key)
+	}
+	if !found {
+		return errors.New("tracestate member missing '='")
+	}
+	if !isValidKey(key) {
+		return errors.New("key doesn't match allowed key pattern")
+	}
+	if !isValidValue(value) {
+		return errors.New("value doesn't match allowed value pattern")
+	}
+
+	member.Key = key
+	member.Value = value
+	return nil
+}
+
+// isValidKey returns true if the key matches the key pattern. It is
+// equivalent to keyPattern but avoids the regex overhead when parsing.
+func isValidKey(key string) bool {
+	if len(key) == 0 || len(key) > 256 {
+		return false
+	}
+	for i := 0; i < len(key); i++ {
+		c := key[i]
+		if c >= 'a' && c <= 'z' || c >= '0' && c <= '9' {
+			continue
+		}
+		if i > 0 && (c == '_' || c == '-' || c == '*' || c == '/' || c == '@') {
+			continue
+		}
+		return false
+	}
+	return true
+}
+
+// isValidValue returns true if the value matches the value pattern. It is
+// equivalent to valuePattern but avoids the regex overhead when parsing.
+func isValidValue(value string) bool {
+	if len(value) == 0 || len(value) > 256 || value[len(value)-1] == ' ' {
+		return false
+	}
+	for i := 0; i < len(value); i++ {
+		c := value[i]
+		if c < 0x20 || c > 0x7e || c == ',' || c == '=' {
+			return false
+		}
+	}
+	return true
+}
+
+// checkASCII returns an error pointing to the first non-ASCII byte of the
+// value, as the value grammar only allows printable ASCII characters
+func checkASCII(value string) error {
+	for i := 0; i < len(value); i++ {
+		if value[i] > 0x7e {
+			return fmt.Errorf("value contains non-ASCII byte at offset %d", i)
+		}
+	}
+	return nil
+}
+
+// validateMember checks that key and value of the member match the format
+// specification
+func validateMember(member TraceStateMember) error {
 	if !keyPattern.MatchString(member.Key) {
 		return errors.New("key doesn't match allowed key pattern")
 	}
+	err := checkASCII(member.Value)
+	if err != nil {
+		return err
+	}
 	if !valuePattern.MatchString(member.Value) {
 		return errors.New("value doesn't match allowed value pattern")
 	}
+	if len(member.Key)+1+len(member.Value) > MaxMemberLength {
+		return ErrMemberTooLong
+	}
+	return nil
+}
 
-	idx := -1
-	for i := range ts.Members {
-		if ts.Members[i].Key == member.Key {
-			idx = i
-			break
+// validate checks that all members are valid, that no key is present more
+// than once and that the list doesn't exceed 32 members
+func (ts *TraceState) validate() error {
+	if len(ts.Members) > MaxMembers {
+		return errors.New("tracestate contains more than 32 members")
+	}
+	keys := map[string]bool{}
+	for _, m := range ts.Members {
+		if m == nil {
+			return errors.New("tracestate contains a nil member")
 		}
+		err := validateMember(*m)
+		if err != nil {
+			return err
+		}
+		if keys[m.Key] {
+			return fmt.Errorf("key '%s' is present more than once", m.Key)
+		}
+		keys[m.Key] = true
+	}
+	return nil
+}
+
+// Mutate will add a new member to beginning of the list and - if the key is
+// already present - remove the old entry
+func (ts *TraceState) Mutate(member TraceStateMember) error {
+	err := validateMember(member)
+	if err != nil {
+		return err
 	}
 
+	idx := ts.IndexOf(member.Key)
+
+	oldValue := ""
 	// If the member already exists in the list, the old entry needs to be
 	// removed first
 	if idx != -1 {
+		oldValue = ts.Members[idx].Value
 		if idx == len(ts.Members)-1 { // If it's the last, it can easily be removed
 			ts.Members = ts.Members[:idx]
 		} else {
@@ -94,14 +347,130 @@ func (ts *TraceState) Mutate(member TraceStateMember) error {
 
 	// If adding an entry would cause the tracestate list to contain more than
 	// 32 list-members the right-most list-member should be removed from the list
-	if len(ts.Members) > 32 {
-		ts.Members = ts.Members[:32]
+	if len(ts.Members) > MaxMembers {
+		ts.Members = ts.Members[:MaxMembers]
+	}
+
+	if OnMutate != nil {
+		OnMutate(member.Key, oldValue, member.Value)
+	}
+	return nil
+}
+
+// ReservedKeys lists tracestate keys owned by other specifications, such as
+// the OpenTelemetry "ot" entry, whose position MutatePreservingReserved
+// doesn't change
+var ReservedKeys = []string{"ot"}
+
+// isReservedKey returns true if the key is listed in ReservedKeys
+func isReservedKey(key string) bool {
+	for _, k := range ReservedKeys {
+		if k == key {
+			return true
+		}
+	}
+	return false
+}
+
+// MutatePreservingReserved works like Mutate, but the updated member is added
+// after reserved members at the beginning of the list instead of in front of
+// them, so that all reserved members keep their position. If the key of the
+// member is reserved itself, it behaves exactly like Mutate.
+func (ts *TraceState) MutatePreservingReserved(member TraceStateMember) error {
+	if isReservedKey(member.Key) {
+		return ts.Mutate(member)
+	}
+
+	reserved := 0
+	for reserved < len(ts.Members) && isReservedKey(ts.Members[reserved].Key) {
+		reserved++
+	}
+
+	err := ts.Mutate(member)
+	if err != nil {
+		return err
+	}
+
+	// Mutate added the member in front of the reserved members, which are
+	// moved back in front of it
+	if reserved > len(ts.Members)-1 {
+		reserved = len(ts.Members) - 1
+	}
+	updated := ts.Members[0]
+	copy(ts.Members, ts.Members[1:reserved+1])
+	ts.Members[reserved] = updated
+	return nil
+}
+
+// Refresh updates the value of the member with the provided key without
+// changing its position in the list. An error is returned if the key is not
+// present or the value doesn't match the allowed format.
+func (ts *TraceState) Refresh(key string, newValue string) error {
+	err := validateMember(TraceStateMember{Key: key, Value: newValue})
+	if err != nil {
+		return err
+	}
+	for _, m := range ts.Members {
+		if m.Key == key {
+			m.Value = newValue
+			return nil
+		}
+	}
+	return errors.New("key is not present in tracestate")
+}
+
+// MapValues replaces the value of each member with the result of fn, e.g. to
+// redact or encrypt values. If fn returns an error or an invalid value, an
+// error is returned and no member is changed.
+func (ts *TraceState) MapValues(fn func(key, value string) (string, error)) error {
+	values := make([]string, len(ts.Members))
+	for i, m := range ts.Members {
+		value, err := fn(m.Key, m.Value)
+		if err != nil {
+			return err
+		}
+		err = validateMember(TraceStateMember{Key: m.Key, Value: value})
+		if err != nil {
+			return err
+		}
+		values[i] = value
+	}
+
+	for i, m := range ts.Members {
+		m.Value = values[i]
 	}
 	return nil
 }
 
+// DedupByKey removes members whose key is already present further left in the
+// list. The left-most (most recent) member of each key is kept and the order
+// is preserved.
+func (ts *TraceState) DedupByKey() {
+	seen := map[string]bool{}
+	members := ts.Members[:0]
+	for _, m := range ts.Members {
+		if !seen[m.Key] {
+			seen[m.Key] = true
+			members = append(members, m)
+		}
+	}
+	ts.Members = members
+}
+
 // String returns the string representation of the tracestate header value
 func (ts *TraceState) String() string {
+	return ts.join(",")
+}
+
+// StringWithSpaces returns the string representation of the tracestate header
+// value with a space after each comma, which is permitted by the
+// specification, e.g. "a=1, b=2"
+func (ts *TraceState) StringWithSpaces() string {
+	return ts.join(", ")
+}
+
+// join returns the members in key=value form separated by sep
+func (ts *TraceState) join(sep string) string {
 	sb := strings.Builder{}
 
 	for i, m := range ts.Members {
@@ -109,7 +478,7 @@ func (ts *TraceState) String() string {
 		sb.WriteString("=")
 		sb.WriteString(m.Value)
 		if i < len(ts.Members)-1 {
-			sb.WriteString(",")
+			sb.WriteString(sep)
 		}
 	}
 
@@ -127,6 +496,155 @@ func (ts *TraceState) MemberValue(memberKey string) string {
 	return ""
 }
 
+// IndexOf returns the position of the member with the provided key in the
+// list or -1 if the key is not present. It is safe to call on a nil
+// TraceState.
+func (ts *TraceState) IndexOf(key string) int {
+	if ts == nil {
+		return -1
+	}
+	for i, m := range ts.Members {
+		if m.Key == key {
+			return i
+		}
+	}
+	return -1
+}
+
+// Len returns the number of members. It is safe to call on a nil TraceState,
+// which has no members.
+func (ts *TraceState) Len() int {
+	if ts == nil {
+		return 0
+	}
+	return len(ts.Members)
+}
+
+// Keys returns the keys of all members in list order
+func (ts *TraceState) Keys() []string {
+	keys := make([]string, 0, len(ts.Members))
+	for _, m := range ts.Members {
+		keys = append(keys, m.Key)
+	}
+	return keys
+}
+
+// OrderedPairs returns a copy of all members as key/value pairs in list order
+func (ts *TraceState) OrderedPairs() [][2]string {
+	pairs := make([][2]string, 0, len(ts.Members))
+	for _, m := range ts.Members {
+		pairs = append(pairs, [2]string{m.Key, m.Value})
+	}
+	return pairs
+}
+
+// MembersForTenant returns the members whose multi-tenant key belongs to the
+// provided tenant, i.e. keys of the form "tenant@system"
+func (ts *TraceState) MembersForTenant(tenant string) []*TraceStateMember {
+	var members []*TraceStateMember
+	for _, m := range ts.Members {
+		if strings.HasPrefix(m.Key, tenant+"@") {
+			members = append(members, m)
+		}
+	}
+	return members
+}
+
+// SubValue returns the value of a sub-field within the member value. Values
+// consisting of sub-fields have the format "key1:value1;key2:value2".
+// The boolean is false if the sub-field is not present.
+func (m *TraceStateMember) SubValue(key string) (string, bool) {
+	for _, field := range strings.Split(m.Value, ";") {
+		k, v, found := strings.Cut(field, ":")
+		if found && k == key {
+			return v, true
+		}
+	}
+	return "", false
+}
+
+// SetSubValue sets the value of a sub-field within the member value. An
+// existing sub-field is replaced in place, otherwise the sub-field is
+// appended. An error is returned if the result isn't a valid member value.
+func (m *TraceStateMember) SetSubValue(key string, value string) error {
+	if key == "" || strings.ContainsAny(key, ":;") || strings.Contains(value, ";") {
+		return errors.New("invalid sub-field")
+	}
+
+	var fields []string
+	if m.Value != "" {
+		fields = strings.Split(m.Value, ";")
+	}
+	replaced := false
+	for i, field := range fields {
+		if k, _, found := strings.Cut(field, ":"); found && k == key {
+			fields[i] = key + ":" + value
+			replaced = true
+			break
+		}
+	}
+	if !replaced {
+		fields = append(fields, key+":"+value)
+	}
+
+	newValue := strings.Join(fields, ";")
+	if !valuePattern.MatchString(newValue) {
+		return errors.New("value doesn't match allowed value pattern")
+	}
+	m.Value = newValue
+	return nil
+}
+
+// clone returns a deep copy of the TraceState
+func (ts *TraceState) clone() *TraceState {
+	c := TraceState{}
+	for _, m := range ts.Members {
+		member := *m
+		c.Members = append(c.Members, &member)
+	}
+	return &c
+}
+
+// PressureRatio returns how close the tracestate is to the member limit, from
+// 0.0 for an empty list to 1.0 for a full list. Once the list is full, adding
+// a new member evicts the right-most one.
+func (ts *TraceState) PressureRatio() float64 {
+	return float64(len(ts.Members)) / MaxMembers
+}
+
+// TraceStateDiff returns the keys that were added, removed or whose value
+// changed between the before and after tracestate. Added and changed keys are
+// in the order of after, removed keys in the order of before. Nil tracestates
+// are treated as empty.
+func TraceStateDiff(before, after *TraceState) (added, removed, changed []string) {
+	beforeValues := map[string]string{}
+	if before != nil {
+		for _, m := range before.Members {
+			beforeValues[m.Key] = m.Value
+		}
+	}
+	afterKeys := map[string]bool{}
+	if after != nil {
+		for _, m := range after.Members {
+			afterKeys[m.Key] = true
+			value, found := beforeValues[m.Key]
+			if !found {
+				added = append(added, m.Key)
+			} else if value != m.Value {
+				changed = append(changed, m.Key)
+			}
+		}
+	}
+	if before != nil {
+		for _, m := range before.Members {
+			if !afterKeys[m.Key] {
+				removed = append(removed, m.Key)
+			}
+		}
+	}
+	return added, removed, changed
+}
+
 // NewEmptyTraceState generates an empty TraceState object
 func NewEmptyTraceState() *TraceState {
 	ts := TraceState{}
@@ -1,7 +1,9 @@
 package tracecontext
 
 import (
+	"errors"
 	"fmt"
+	"strings"
 	"testing"
 )
 
@@ -230,3 +232,488 @@ func TestMemberValueNotFound(t *testing.T) {
 		t.Error("no empty value returned")
 	}
 }
+
+func TestKeys(t *testing.T) {
+	ts := NewEmptyTraceState()
+	ts.Mutate(TraceStateMember{Key: "member1", Value: "value1"})
+	ts.Mutate(TraceStateMember{Key: "member2", Value: "value2"})
+	ts.Mutate(TraceStateMember{Key: "member3", Value: "value3"})
+
+	keys := ts.Keys()
+
+	if len(keys) != 3 || keys[0] != "member3" || keys[1] != "member2" || keys[2] != "member1" {
+		t.Errorf("Wrong keys returned: %v", keys)
+	}
+}
+
+func TestMutateNonASCIIValue(t *testing.T) {
+	ts := NewEmptyTraceState()
+
+	err := ts.Mutate(TraceStateMember{Key: "key", Value: "val🙂"})
+
+	if err == nil || err.Error() != "value contains non-ASCII byte at offset 3" {
+		t.Error("Non-ASCII value didn't cause the expected error:", err)
+	}
+}
+
+func TestParseTraceStateNonASCIIValue(t *testing.T) {
+	_, err := ParseTraceState("vendor1=val1, key=v🙂")
+
+	if err == nil || err.Error() != "value contains non-ASCII byte at offset 1" {
+		t.Error("Non-ASCII value didn't cause the expected error:", err)
+	}
+}
+
+func TestMutatePreservingReserved(t *testing.T) {
+	ts, _ := ParseTraceState("ot=th:8,acme=1")
+
+	err := ts.MutatePreservingReserved(TraceStateMember{Key: "acme", Value: "2"})
+
+	if err != nil {
+		t.Error("Failed to mutate:", err)
+	}
+	if s := ts.String(); s != "ot=th:8,acme=2" {
+		t.Errorf("Wrong tracestate: '%s'", s)
+	}
+}
+
+func TestMutatePreservingReservedNotLeading(t *testing.T) {
+	ts, _ := ParseTraceState("other=1,ot=th:8,acme=1")
+
+	ts.MutatePreservingReserved(TraceStateMember{Key: "acme", Value: "2"})
+
+	if s := ts.String(); s != "acme=2,other=1,ot=th:8" {
+		t.Errorf("Wrong tracestate: '%s'", s)
+	}
+}
+
+func TestRefresh(t *testing.T) {
+	ts, _ := ParseTraceState("member1=value1,member2=value2,member3=value3")
+
+	err := ts.Refresh("member2", "newVal")
+
+	if err != nil {
+		t.Error("Failed to refresh member:", err)
+	}
+	if s := ts.String(); s != "member1=value1,member2=newVal,member3=value3" {
+		t.Errorf("Wrong string value returned: '%s'", s)
+	}
+}
+
+func TestRefreshInvalid(t *testing.T) {
+	ts, _ := ParseTraceState("member1=value1")
+
+	if err := ts.Refresh("member2", "newVal"); err == nil {
+		t.Error("Missing key didn't cause an error")
+	}
+	if err := ts.Refresh("member1", "new,Val"); err == nil {
+		t.Error("Illegal value didn't cause an error")
+	}
+	if ts.MemberValue("member1") != "value1" {
+		t.Error("value was changed")
+	}
+}
+
+func TestSubValue(t *testing.T) {
+	m := TraceStateMember{Key: "acme", Value: "r:0.25;p:8"}
+
+	if v, ok := m.SubValue("r"); !ok || v != "0.25" {
+		t.Errorf("Wrong sub-value returned for r: '%s'", v)
+	}
+	if v, ok := m.SubValue("p"); !ok || v != "8" {
+		t.Errorf("Wrong sub-value returned for p: '%s'", v)
+	}
+	if _, ok := m.SubValue("x"); ok {
+		t.Error("Missing sub-value found")
+	}
+}
+
+func TestSetSubValue(t *testing.T) {
+	m := TraceStateMember{Key: "acme", Value: "r:0.25;p:8"}
+
+	if err := m.SetSubValue("r", "0.5"); err != nil {
+		t.Error("Failed to set sub-value:", err)
+	}
+	if err := m.SetSubValue("x", "1"); err != nil {
+		t.Error("Failed to set sub-value:", err)
+	}
+	if m.Value != "r:0.5;p:8;x:1" {
+		t.Errorf("Wrong value: '%s'", m.Value)
+	}
+
+	empty := TraceStateMember{Key: "acme"}
+	empty.SetSubValue("r", "0.25")
+	if empty.Value != "r:0.25" {
+		t.Errorf("Wrong value: '%s'", empty.Value)
+	}
+
+	if err := m.SetSubValue("r", "a,b"); err == nil {
+		t.Error("Illegal value didn't cause an error")
+	}
+	if m.Value != "r:0.5;p:8;x:1" {
+		t.Error("value was changed")
+	}
+}
+
+func TestNormalizeTraceStateString(t *testing.T) {
+	s, err := NormalizeTraceStateString("a=1,  b=2 ")
+
+	if err != nil {
+		t.Error("Failed to normalize tracestate:", err)
+	}
+	if s != "a=1,b=2" {
+		t.Errorf("Wrong normalized value: '%s'", s)
+	}
+
+	if _, err := NormalizeTraceStateString("a=1,B=2"); err == nil {
+		t.Error("Invalid tracestate normalized")
+	}
+}
+
+func TestParseTraceStateMemberErrors(t *testing.T) {
+	cases := map[string]string{
+		"a=1,b":     "tracestate member missing '='",
+		"a=1,B=2":   "key doesn't match allowed key pattern",
+		"a=1,b=2=3": "value doesn't match allowed value pattern",
+	}
+
+	for input, expected := range cases {
+		_, err := ParseTraceState(input + ",c=3")
+		if err == nil || err.Error() != expected {
+			t.Errorf("Wrong error for '%s': %v", input, err)
+		}
+	}
+}
+
+func TestParseTraceStateTruncated(t *testing.T) {
+	padding := strings.Repeat("x=1,", (MaxTraceStateHeaderBytes-MaxMemberLength)/4)
+	for _, input := range []string{"a=1,b=", "a=1,b", "a="} {
+		if _, err := ParseTraceState(padding + input); err != ErrTruncatedTraceState {
+			t.Errorf("Wrong error for truncated tracestate '%s': %v", input, err)
+		}
+	}
+
+	for _, input := range []string{"a=,b=1", "a=1,B=", "a=1,b=2 3=", "a=1,=2"} {
+		if _, err := ParseTraceState(padding + input); err == nil || err == ErrTruncatedTraceState {
+			t.Errorf("Wrong error for malformed tracestate '%s': %v", input, err)
+		}
+	}
+
+	for _, input := range []string{"foo", "a=1,b=", "a=1,b"} {
+		if _, err := ParseTraceState(input); err == nil || err == ErrTruncatedTraceState {
+			t.Errorf("Wrong error for short malformed tracestate '%s': %v", input, err)
+		}
+	}
+}
+
+func TestParseTraceStateN(t *testing.T) {
+	members := make([]string, 40)
+	for i := range members {
+		members[i] = fmt.Sprintf("vendor%d=val%d", i, i)
+	}
+
+	ts, err := ParseTraceStateN(strings.Join(members, ","), 10)
+
+	if err != nil {
+		t.Error("Failed to parse tracestate:", err)
+	}
+	if ts.Len() != 10 {
+		t.Errorf("Wrong number of members retained: %d", ts.Len())
+	}
+	if ts.Members[0].Key != "vendor0" || ts.Members[9].Key != "vendor9" {
+		t.Error("Wrong members retained")
+	}
+
+	ts, _ = ParseTraceStateN(strings.Join(members, ","), 0)
+	if ts.Len() != 40 {
+		t.Errorf("Members discarded without limit: %d", ts.Len())
+	}
+}
+
+func TestParseTraceStateReader(t *testing.T) {
+	ts, err := ParseTraceStateReader(strings.NewReader("a=1,b=2"), 7)
+
+	if err != nil {
+		t.Error("Failed to parse tracestate:", err)
+	}
+	if ts.String() != "a=1,b=2" {
+		t.Errorf("Wrong tracestate: '%s'", ts.String())
+	}
+}
+
+func TestParseTraceStateReaderTooLarge(t *testing.T) {
+	_, err := ParseTraceStateReader(strings.NewReader("a=1,b=2"), 6)
+
+	if err != ErrTraceStateTooLarge {
+		t.Errorf("Wrong error for oversized input: %v", err)
+	}
+}
+
+func TestValidateTraceStateString(t *testing.T) {
+	errs := ValidateTraceStateString("a=1,B=2,c=3,d")
+
+	if len(errs) != 2 {
+		t.Fatalf("Wrong number of errors: %v", errs)
+	}
+	if !strings.HasPrefix(errs[0].Error(), "member 1:") || !strings.HasPrefix(errs[1].Error(), "member 3:") {
+		t.Errorf("Wrong member indices: %v", errs)
+	}
+
+	if errs := ValidateTraceStateString("a=1, b=2"); len(errs) != 0 {
+		t.Errorf("Valid tracestate reported errors: %v", errs)
+	}
+}
+
+func TestDedupByKey(t *testing.T) {
+	ts, _ := ParseTraceState("a=1,b=2,a=3")
+
+	ts.DedupByKey()
+
+	if s := ts.String(); s != "a=1,b=2" {
+		t.Errorf("Wrong string value returned: '%s'", s)
+	}
+}
+
+func TestStringWithSpaces(t *testing.T) {
+	ts, _ := ParseTraceState("a=1,b=2")
+
+	if s := ts.StringWithSpaces(); s != "a=1, b=2" {
+		t.Errorf("Wrong string value returned: '%s'", s)
+	}
+	if s := ts.String(); s != "a=1,b=2" {
+		t.Errorf("Wrong string value returned: '%s'", s)
+	}
+}
+
+func TestPressureRatio(t *testing.T) {
+	expected := map[int]float64{0: 0.0, 16: 0.5, 32: 1.0}
+
+	for n, ratio := range expected {
+		ts := NewEmptyTraceState()
+		for i := 0; i < n; i++ {
+			ts.Mutate(TraceStateMember{Key: fmt.Sprintf("m%d", i), Value: "v"})
+		}
+
+		if ts.PressureRatio() != ratio {
+			t.Errorf("Wrong pressure ratio %f for %d members", ts.PressureRatio(), n)
+		}
+	}
+}
+
+func TestMembersForTenant(t *testing.T) {
+	ts, _ := ParseTraceState("t123@acme=val1,acme=val2,t456@acme=val3,t123@other=val4,t1234@acme=val5")
+
+	members := ts.MembersForTenant("t123")
+
+	if len(members) != 2 {
+		t.Fatalf("Incorrect number of members %d", len(members))
+	}
+	if members[0].Key != "t123@acme" || members[1].Key != "t123@other" {
+		t.Error("Wrong members returned")
+	}
+	if len(ts.MembersForTenant("t789")) != 0 {
+		t.Error("Members returned for unknown tenant")
+	}
+}
+
+func TestMutateMemberTooLong(t *testing.T) {
+	ts := NewEmptyTraceState()
+
+	err := ts.Mutate(TraceStateMember{Key: "k" + strings.Repeat("a", 199), Value: strings.Repeat("v", 100)})
+	if err != ErrMemberTooLong {
+		t.Error("Too long member didn't cause the expected error:", err)
+	}
+
+	err = ts.Mutate(TraceStateMember{Key: "k" + strings.Repeat("a", 149), Value: strings.Repeat("v", 105)})
+	if err != nil {
+		t.Error("Member just under the limit not accepted:", err)
+	}
+	if len(ts.Members) != 1 {
+		t.Errorf("Incorrect length %d after mutate", len(ts.Members))
+	}
+}
+
+func TestOnMutate(t *testing.T) {
+	var calls [][3]string
+	OnMutate = func(key, oldValue, newValue string) {
+		calls = append(calls, [3]string{key, oldValue, newValue})
+	}
+	defer func() { OnMutate = nil }()
+
+	ts := NewEmptyTraceState()
+	ts.Mutate(TraceStateMember{Key: "member1", Value: "value1"})
+	ts.Mutate(TraceStateMember{Key: "member1", Value: "newVal"})
+	ts.Mutate(TraceStateMember{Key: "Member1", Value: "value1"})
+
+	if len(calls) != 2 {
+		t.Fatalf("Hook called %d times", len(calls))
+	}
+	if calls[0] != [3]string{"member1", "", "value1"} {
+		t.Errorf("Wrong hook arguments on add: %v", calls[0])
+	}
+	if calls[1] != [3]string{"member1", "value1", "newVal"} {
+		t.Errorf("Wrong hook arguments on replace: %v", calls[1])
+	}
+}
+
+func TestTraceStateDiff(t *testing.T) {
+	before, _ := ParseTraceState("member1=value1,member2=value2,member3=value3")
+	after, _ := ParseTraceState("member4=value4,member1=newVal,member3=value3")
+
+	added, removed, changed := TraceStateDiff(before, after)
+
+	if len(added) != 1 || added[0] != "member4" {
+		t.Errorf("Wrong added keys: %v", added)
+	}
+	if len(removed) != 1 || removed[0] != "member2" {
+		t.Errorf("Wrong removed keys: %v", removed)
+	}
+	if len(changed) != 1 || changed[0] != "member1" {
+		t.Errorf("Wrong changed keys: %v", changed)
+	}
+}
+
+func TestTraceStateDiffNil(t *testing.T) {
+	ts, _ := ParseTraceState("member1=value1")
+
+	added, removed, changed := TraceStateDiff(nil, ts)
+	if len(added) != 1 || len(removed) != 0 || len(changed) != 0 {
+		t.Error("Wrong diff against nil before")
+	}
+
+	added, removed, changed = TraceStateDiff(ts, nil)
+	if len(added) != 0 || len(removed) != 1 || len(changed) != 0 {
+		t.Error("Wrong diff against nil after")
+	}
+}
+
+func TestParseTraceStateTooLarge(t *testing.T) {
+	s := strings.Repeat("a=1,", 256*1024)
+
+	_, err := ParseTraceState(s)
+
+	if !errors.Is(err, ErrTraceStateTooLarge) {
+		t.Error("Too large tracestate didn't cause the expected error:", err)
+	}
+}
+
+func BenchmarkParseTraceStateTooLarge(b *testing.B) {
+	s := strings.Repeat("a=1,", 256*1024)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		ParseTraceState(s)
+	}
+}
+
+func TestParseTraceStateSizeLimitDisabled(t *testing.T) {
+	MaxTraceStateHeaderBytes = 0
+	defer func() { MaxTraceStateHeaderBytes = 8192 }()
+
+	_, err := ParseTraceState(strings.Repeat("a=1,", 4096))
+
+	if err != nil {
+		t.Error("Failed to parse tracestate without size limit:", err)
+	}
+}
+
+func TestOrderedPairs(t *testing.T) {
+	ts, _ := ParseTraceState("member2=value2,member1=value1")
+
+	pairs := ts.OrderedPairs()
+
+	if len(pairs) != 2 || pairs[0] != [2]string{"member2", "value2"} || pairs[1] != [2]string{"member1", "value1"} {
+		t.Errorf("Wrong pairs returned: %v", pairs)
+	}
+
+	pairs[0][1] = "newVal"
+	if ts.MemberValue("member2") != "value2" {
+		t.Error("Modifying the pairs changed the tracestate")
+	}
+}
+
+func TestParseTraceStateLowercaseKeys(t *testing.T) {
+	if _, err := ParseTraceState("Vendor=1"); err == nil {
+		t.Error("Uppercase key parsed without lenient mode")
+	}
+
+	LowercaseKeys = true
+	defer func() { LowercaseKeys = false }()
+
+	ts, err := ParseTraceState("Vendor=1")
+	if err != nil {
+		t.Error("Failed to parse tracestate:", err)
+	}
+	if ts.Members[0].Key != "vendor" {
+		t.Errorf("Key not lowercased: '%s'", ts.Members[0].Key)
+	}
+
+	if _, err := ParseTraceState("Vendor!=1"); err == nil {
+		t.Error("Invalid key parsed in lenient mode")
+	}
+}
+
+func TestLen(t *testing.T) {
+	var nilTs *TraceState
+	populated, _ := ParseTraceState("member1=value1,member2=value2")
+
+	if nilTs.Len() != 0 {
+		t.Error("Wrong length of nil tracestate")
+	}
+	if NewEmptyTraceState().Len() != 0 {
+		t.Error("Wrong length of empty tracestate")
+	}
+	if populated.Len() != 2 {
+		t.Error("Wrong length of populated tracestate")
+	}
+}
+
+func TestMapValues(t *testing.T) {
+	ts, _ := ParseTraceState("member1=value1,member2=value2")
+
+	err := ts.MapValues(func(key, value string) (string, error) {
+		return strings.ToUpper(value), nil
+	})
+
+	if err != nil {
+		t.Error("Failed to map values:", err)
+	}
+	if s := ts.String(); s != "member1=VALUE1,member2=VALUE2" {
+		t.Errorf("Wrong string value returned: '%s'", s)
+	}
+}
+
+func TestMapValuesInvalid(t *testing.T) {
+	ts, _ := ParseTraceState("member1=value1,member2=value2")
+
+	err := ts.MapValues(func(key, value string) (string, error) {
+		if key == "member2" {
+			return "a,b", nil
+		}
+		return "newVal", nil
+	})
+
+	if err == nil {
+		t.Error("Illegal value didn't cause an error")
+	}
+	if s := ts.String(); s != "member1=value1,member2=value2" {
+		t.Errorf("Values were changed: '%s'", s)
+	}
+}
+
+func TestIndexOf(t *testing.T) {
+	ts, _ := ParseTraceState("member1=value1,member2=value2,member3=value3")
+
+	for i, key := range []string{"member1", "member2", "member3"} {
+		if idx := ts.IndexOf(key); idx != i {
+			t.Errorf("Wrong index of '%s': %d", key, idx)
+		}
+	}
+	if idx := ts.IndexOf("member4"); idx != -1 {
+		t.Errorf("Wrong index of absent key: %d", idx)
+	}
+
+	var nilTs *TraceState
+	if idx := nilTs.IndexOf("member1"); idx != -1 {
+		t.Errorf("Wrong index in nil tracestate: %d", idx)
+	}
+}
@@ -12,3 +12,54 @@ func randomHex(n int) (string, error) {
 	}
 	return hex.EncodeToString(bytes), nil
 }
+
+// isLowerHex returns true if b only consists of lowercase hex characters
+func isLowerHex[T ~string | ~[]byte](b T) bool {
+	for i := 0; i < len(b); i++ {
+		if !(b[i] >= '0' && b[i] <= '9' || b[i] >= 'a' && b[i] <= 'f') {
+			return false
+		}
+	}
+	return true
+}
+
+// isZero returns true if b only consists of '0' characters
+func isZero[T ~string | ~[]byte](b T) bool {
+	for i := 0; i < len(b); i++ {
+		if b[i] != '0' {
+			return false
+		}
+	}
+	return true
+}
+
+// appendSplit appends the substrings of s separated by sep to dst, like
+// strings.Split but without allocating if dst has sufficient capacity
+func appendSplit(dst []string, s string, sep byte) []string {
+	start := 0
+	for i := 0; i < len(s); i++ {
+		if s[i] == sep {
+			dst = append(dst, s[start:i])
+			start = i + 1
+		}
+	}
+	return append(dst, s[start:])
+}
+
+// appendHexByte appends the two lowercase hex characters of v to b
+func appendHexByte(b []byte, v byte) []byte {
+	const digits = "0123456789abcdef"
+	return append(b, digits[v>>4], digits[v&0x0f])
+}
+
+// hexToByte converts two lowercase hex characters into the byte they encode
+func hexToByte(hi byte, lo byte) byte {
+	return hexNibble(hi)<<4 | hexNibble(lo)
+}
+
+func hexNibble(c byte) byte {
+	if c >= 'a' {
+		return c - 'a' + 10
+	}
+	return c - '0'
+}